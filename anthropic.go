@@ -3,19 +3,29 @@ package mockllm
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 )
 
 // AnthropicProvider handles Anthropic request/response mocking
 type AnthropicProvider struct {
-	mocks []AnthropicMock
+	mocks           []AnthropicMock
+	recorder        *RecordingProvider
+	tokenCounter    *TokenCounter
+	injector        *Injector
+	defaultBehavior Behavior
 }
 
-// NewAnthropicProvider creates a new Anthropic AnthropicProvider with the given mocks
-func NewAnthropicProvider(mocks []AnthropicMock) *AnthropicProvider {
-	return &AnthropicProvider{mocks: mocks}
+// NewAnthropicProvider creates a new Anthropic AnthropicProvider with the
+// given mocks. recorder may be nil, in which case unmatched requests are
+// always a 404. tokenCounter may be nil, in which case usage blocks are
+// left as-is. defaultBehavior fills any zero-valued field of a mock's own
+// Behavior.
+func NewAnthropicProvider(mocks []AnthropicMock, recorder *RecordingProvider, tokenCounter *TokenCounter, injector *Injector, defaultBehavior Behavior) *AnthropicProvider {
+	return &AnthropicProvider{mocks: mocks, recorder: recorder, tokenCounter: tokenCounter, injector: injector, defaultBehavior: defaultBehavior}
 }
 
 // Handle processes an Anthropic messages request
@@ -32,8 +42,14 @@ func (p *AnthropicProvider) Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse the incoming request into SDK type
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	var requestBody anthropic.MessageNewParams
-	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+	if err := json.Unmarshal(body, &requestBody); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
 		return
 	}
@@ -41,13 +57,111 @@ func (p *AnthropicProvider) Handle(w http.ResponseWriter, r *http.Request) {
 	// Find a matching mock
 	mock := p.findMatchingMock(requestBody)
 	if mock == nil {
+		if p.recorder != nil && p.recorder.mode != RecordModeReplay && p.handleRecord(w, r, requestBody) {
+			return
+		}
+
 		http.Error(w, "No matching mock found", http.StatusNotFound)
 		return
 	}
 
+	p.tokenCounter.ApplyAnthropic(&mock.Response, requestBody.Messages)
+
+	behavior := mock.Behavior.merge(p.defaultBehavior)
+	if p.injector.Apply(w, "anthropic:"+mock.Name, "anthropic", behavior) {
+		return
+	}
+
+	if requestWantsStream(body) && len(mock.StreamChunks) > 0 {
+		p.handleStream(w, *mock, requestBody.Messages, behavior.TimeoutPercent)
+		return
+	}
+
+	if ShouldTimeout(behavior.TimeoutPercent) {
+		DropConnection(w)
+		return
+	}
+
 	handleNonStreamingResponse(w, mock.Response)
 }
 
+// handleStream emits the mock's StreamChunks as the documented Anthropic SSE
+// event sequence, one "event:"/"data:" pair per streamed event. When the
+// TokenCounter's UsageMode is "computed" or "augment", the output token
+// delta is recomputed (or, for "augment", filled only if zero) on the
+// "message_delta" event from the concatenated content_block_delta text seen
+// so far, the same incremental accounting a real streaming response does.
+func (p *AnthropicProvider) handleStream(w http.ResponseWriter, mock AnthropicMock, messages []anthropic.MessageParam, timeoutPercent float64) {
+	frames := make([]string, 0, len(mock.StreamChunks))
+	seenText := ""
+
+	for _, event := range mock.StreamChunks {
+		if event.Delta.Text != "" {
+			seenText += event.Delta.Text
+		}
+
+		if p.tokenCounter != nil && event.Type == "message_delta" {
+			_, completion := p.tokenCounter.fields(textOf(messages), seenText, 0, event.Usage.OutputTokens)
+			event.Usage.OutputTokens = completion
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to encode event: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		frames = append(frames, fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, data))
+	}
+
+	handleStreamingResponse(w, frames, mock.ChunkDelay, timeoutPercent)
+}
+
+// handleRecord forwards an unmatched request to the real upstream provider,
+// records the exchange, and proxies the upstream response back to the
+// client. It reports false, without forwarding, if the request has no
+// messages or can't be re-encoded.
+func (p *AnthropicProvider) handleRecord(w http.ResponseWriter, r *http.Request, requestBody anthropic.MessageNewParams) bool {
+	if len(requestBody.Messages) == 0 {
+		return false
+	}
+
+	reqBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return false
+	}
+
+	status, respBody, respHeader, err := p.recorder.Forward(r, "/v1/messages", reqBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to forward to upstream: %v", err), http.StatusBadGateway)
+		return true
+	}
+
+	lastMessage, err := json.Marshal(requestBody.Messages[len(requestBody.Messages)-1])
+	if err != nil {
+		lastMessage = []byte("{}")
+	}
+
+	streamChunks := splitSSEFrames(respBody, respHeader.Get("Content-Type"))
+	if len(streamChunks) > 0 {
+		if err := p.recorder.Record("anthropic", "message", string(lastMessage), r.Header.Clone(), nil, streamChunks); err != nil {
+			fmt.Printf("Failed to record cassette entry: %v\n", err)
+		}
+	} else if err := p.recorder.Record("anthropic", "message", string(lastMessage), r.Header.Clone(), respBody, nil); err != nil {
+		fmt.Printf("Failed to record cassette entry: %v\n", err)
+	}
+
+	if ct := respHeader.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	w.Write(respBody) //nolint:errcheck
+
+	return true
+}
+
 // findMatchingMock finds the first mock that matches the request
 func (p *AnthropicProvider) findMatchingMock(request anthropic.MessageNewParams) *AnthropicMock {
 	for _, mock := range p.mocks {
@@ -58,11 +172,91 @@ func (p *AnthropicProvider) findMatchingMock(request anthropic.MessageNewParams)
 	return nil
 }
 
-// requestsMatch checks if two requests are equivalent
+// requestsMatch checks if a request matches expected, per expected.MatchType.
 func (p *AnthropicProvider) requestsMatch(expected AnthropicRequestMatch, actual anthropic.MessageNewParams) bool {
-	if len(actual.Messages) == 0 {
+	if expected.SystemContains != "" && !strings.Contains(textOf(actual.System), expected.SystemContains) {
 		return false
 	}
 
-	return compareMessages(expected.MatchType, expected.Message, actual.Messages[len(actual.Messages)-1])
+	switch expected.MatchType {
+	case MatchTypeAll:
+		for _, sub := range expected.Matchers {
+			if !p.requestsMatch(sub, actual) {
+				return false
+			}
+		}
+
+		return true
+	case MatchTypeAny:
+		for _, sub := range expected.Matchers {
+			if p.requestsMatch(sub, actual) {
+				return true
+			}
+		}
+
+		return false
+	case MatchTypeJSONPath:
+		return matchJSONPath(actual, expected.JSONPath, expected.Value, expected.Regex)
+	case MatchTypeRegex:
+		if len(actual.Messages) == 0 {
+			return false
+		}
+
+		return matchRegex(expected.Regex, textOf(actual.Messages[len(actual.Messages)-1]))
+	case MatchTypeToolCall:
+		return toolCallMatches(actual, expected.ToolName)
+	default:
+		if len(actual.Messages) == 0 {
+			return false
+		}
+
+		return compareMessages(expected.MatchType, expected.Message, actual.Messages[len(actual.Messages)-1])
+	}
+}
+
+// AnthropicCountTokensProvider handles Anthropic /v1/messages/count_tokens
+// request/response mocking.
+type AnthropicCountTokensProvider struct {
+	mocks []AnthropicCountTokensMock
+}
+
+// NewAnthropicCountTokensProvider creates a new AnthropicCountTokensProvider
+// with the given mocks.
+func NewAnthropicCountTokensProvider(mocks []AnthropicCountTokensMock) *AnthropicCountTokensProvider {
+	return &AnthropicCountTokensProvider{mocks: mocks}
+}
+
+// Handle processes an Anthropic count_tokens request.
+func (p *AnthropicCountTokensProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("x-api-key") == "" {
+		http.Error(w, "Missing x-api-key header", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get("anthropic-version") == "" {
+		http.Error(w, "Missing anthropic-version header", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody anthropic.MessageCountTokensParams
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(requestBody.Messages) == 0 {
+		http.Error(w, "No matching mock found", http.StatusNotFound)
+		return
+	}
+
+	lastMessage := requestBody.Messages[len(requestBody.Messages)-1]
+
+	for _, mock := range p.mocks {
+		if compareMessages(mock.Match.MatchType, mock.Match.Message, lastMessage) {
+			handleNonStreamingResponse(w, mock.Response)
+			return
+		}
+	}
+
+	http.Error(w, "No matching mock found", http.StatusNotFound)
 }
@@ -0,0 +1,238 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Behavior injects latency, failures, rate limiting, and mid-response
+// timeouts ahead of a mock's normal response, so client retry/backoff code
+// can be exercised against predictable flakiness.
+type Behavior struct {
+	// LatencyMin/LatencyMax bound a random delay applied before responding.
+	// If LatencyMax is zero or not greater than LatencyMin, LatencyMin is
+	// used as a fixed delay.
+	LatencyMin time.Duration `json:"latency_min,omitempty"`
+	LatencyMax time.Duration `json:"latency_max,omitempty"`
+	// FailurePercent is the 0-100 chance of returning FailureStatus with a
+	// provider-shaped error body instead of the mocked response.
+	FailurePercent float64 `json:"failure_percent,omitempty"`
+	// FailureStatus is the HTTP status returned when the FailurePercent
+	// roll hits. Defaults to 500.
+	FailureStatus int `json:"failure_status,omitempty"`
+	// FailureType/FailureMessage populate the provider-shaped error body.
+	FailureType    string `json:"failure_type,omitempty"`
+	FailureMessage string `json:"failure_message,omitempty"`
+	// RateLimit, if set, returns 429 once its token bucket is exhausted.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+	// TimeoutPercent is the 0-100 chance of dropping the connection
+	// mid-response (mid-SSE frame when streaming) instead of completing it.
+	TimeoutPercent float64 `json:"timeout_percent,omitempty"`
+	// Override, if true, uses this Behavior's fields as-is instead of
+	// merge filling its zero-valued fields from Config.DefaultBehavior.
+	// Without this, a mock can't pin itself as reliable (e.g. explicit
+	// zero FailurePercent/TimeoutPercent) against a flaky server-wide
+	// default, since a zero field is indistinguishable from an unset one.
+	Override bool `json:"override,omitempty"`
+}
+
+// RateLimit configures a token bucket: Limit requests are allowed per
+// Window, refilling continuously.
+type RateLimit struct {
+	Limit  int           `json:"limit"`
+	Window time.Duration `json:"window"`
+}
+
+// merge fills any zero-valued field of b from def, so a server-wide default
+// Behavior can be overridden per-mock. If b.Override is set, b is returned
+// as-is, so a mock can pin itself to explicit zero-valued fields (e.g. no
+// failures) despite a nonzero default.
+func (b Behavior) merge(def Behavior) Behavior {
+	if b.Override {
+		return b
+	}
+
+	if b.LatencyMin == 0 && b.LatencyMax == 0 {
+		b.LatencyMin, b.LatencyMax = def.LatencyMin, def.LatencyMax
+	}
+
+	if b.FailurePercent == 0 {
+		b.FailurePercent, b.FailureStatus = def.FailurePercent, def.FailureStatus
+		b.FailureType, b.FailureMessage = def.FailureType, def.FailureMessage
+	}
+
+	if b.RateLimit == nil {
+		b.RateLimit = def.RateLimit
+	}
+
+	if b.TimeoutPercent == 0 {
+		b.TimeoutPercent = def.TimeoutPercent
+	}
+
+	return b
+}
+
+// tokenBucket is a simple continuously-refilling token bucket backing
+// Behavior.RateLimit.
+type tokenBucket struct {
+	mu         sync.Mutex
+	limit      int
+	window     time.Duration
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(limit int, window time.Duration) *tokenBucket {
+	return &tokenBucket{limit: limit, window: window, tokens: float64(limit), lastRefill: time.Now()}
+}
+
+// take reports whether a request may proceed, along with the tokens
+// remaining and, if exhausted, how long until a token is available again.
+func (tb *tokenBucket) take() (ok bool, remaining int, retryAfter time.Duration) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(tb.lastRefill)
+	tb.lastRefill = now
+
+	tb.tokens += elapsed.Seconds() / tb.window.Seconds() * float64(tb.limit)
+	if tb.tokens > float64(tb.limit) {
+		tb.tokens = float64(tb.limit)
+	}
+
+	if tb.tokens < 1 {
+		perToken := tb.window / time.Duration(tb.limit)
+		return false, 0, time.Duration((1 - tb.tokens) * float64(perToken))
+	}
+
+	tb.tokens--
+
+	return true, int(tb.tokens), 0
+}
+
+// Injector applies Behavior's latency, rate-limit, and failure injection
+// ahead of a mock's normal response. Timeout injection is handled by the
+// caller via ShouldTimeout/DropConnection, since its effect differs between
+// streaming and non-streaming responses.
+type Injector struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewInjector creates an Injector.
+func NewInjector() *Injector {
+	return &Injector{buckets: map[string]*tokenBucket{}}
+}
+
+// Apply injects latency, rate-limiting, and failure behavior for key
+// (typically "<provider>:<mock name>"). It reports true if it fully wrote a
+// response, in which case the caller must not write anything further.
+func (inj *Injector) Apply(w http.ResponseWriter, key, provider string, b Behavior) bool {
+	if b.LatencyMin > 0 || b.LatencyMax > 0 {
+		delay := b.LatencyMin
+		if b.LatencyMax > b.LatencyMin {
+			delay += time.Duration(rand.Int63n(int64(b.LatencyMax - b.LatencyMin)))
+		}
+
+		time.Sleep(delay)
+	}
+
+	if b.RateLimit != nil && b.RateLimit.Limit > 0 {
+		ok, remaining, retryAfter := inj.bucket(key, *b.RateLimit).take()
+		if !ok {
+			w.Header().Set("Retry-After", fmt.Sprintf("%.0f", retryAfter.Seconds()))
+			w.Header().Set("x-ratelimit-limit", fmt.Sprintf("%d", b.RateLimit.Limit))
+			w.Header().Set("x-ratelimit-remaining", "0")
+			w.Header().Set("x-ratelimit-reset", fmt.Sprintf("%.0f", retryAfter.Seconds()))
+			writeProviderError(w, provider, http.StatusTooManyRequests, "rate_limit_error", "rate limit exceeded")
+
+			return true
+		}
+
+		w.Header().Set("x-ratelimit-limit", fmt.Sprintf("%d", b.RateLimit.Limit))
+		w.Header().Set("x-ratelimit-remaining", fmt.Sprintf("%d", remaining))
+	}
+
+	if b.FailurePercent > 0 && rand.Float64()*100 < b.FailurePercent {
+		status := b.FailureStatus
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+
+		errType := b.FailureType
+		if errType == "" {
+			errType = "internal_server_error"
+		}
+
+		message := b.FailureMessage
+		if message == "" {
+			message = "injected failure"
+		}
+
+		writeProviderError(w, provider, status, errType, message)
+
+		return true
+	}
+
+	return false
+}
+
+// ShouldTimeout rolls a 0-100 percent chance, as used by Behavior.TimeoutPercent.
+func ShouldTimeout(percent float64) bool {
+	return percent > 0 && rand.Float64()*100 < percent
+}
+
+// DropConnection hijacks and closes the underlying connection without
+// writing a response, simulating a client-visible timeout.
+func DropConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return
+	}
+
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+
+	conn.Close() //nolint:errcheck
+}
+
+func (inj *Injector) bucket(key string, rl RateLimit) *tokenBucket {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+
+	b, ok := inj.buckets[key]
+	if !ok {
+		b = newTokenBucket(rl.Limit, rl.Window)
+		inj.buckets[key] = b
+	}
+
+	return b
+}
+
+// writeProviderError writes status with a provider-shaped error body:
+// {"error": {"type", "message"}} for OpenAI/Anthropic, {"error": {"code",
+// "status", "message"}} for Google.
+func writeProviderError(w http.ResponseWriter, provider string, status int, errType, message string) {
+	var body map[string]any
+
+	switch provider {
+	case "google":
+		body = map[string]any{"error": map[string]any{"code": status, "status": errType, "message": message}}
+	default:
+		body = map[string]any{"error": map[string]any{"type": errType, "message": message}}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,124 @@
+package mockllm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllowsUpToLimit(t *testing.T) {
+	tb := newTokenBucket(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		ok, _, _ := tb.take()
+		if !ok {
+			t.Fatalf("request %d: expected to be allowed within the limit", i)
+		}
+	}
+
+	ok, remaining, retryAfter := tb.take()
+	if ok {
+		t.Fatal("expected the 4th request to be rate-limited")
+	}
+
+	if remaining != 0 {
+		t.Errorf("remaining = %d, want 0", remaining)
+	}
+
+	if retryAfter <= 0 {
+		t.Errorf("retryAfter = %v, want > 0", retryAfter)
+	}
+}
+
+func TestTokenBucketRefillsOverTime(t *testing.T) {
+	tb := newTokenBucket(1, time.Second)
+
+	ok, _, _ := tb.take()
+	if !ok {
+		t.Fatal("expected the first request to be allowed")
+	}
+
+	if ok, _, _ := tb.take(); ok {
+		t.Fatal("expected the bucket to be exhausted")
+	}
+
+	// Backdate lastRefill to simulate the window having fully elapsed.
+	tb.lastRefill = time.Now().Add(-time.Second)
+
+	if ok, _, _ := tb.take(); !ok {
+		t.Fatal("expected a token to be available after a full window elapsed")
+	}
+}
+
+func TestTokenBucketNeverExceedsLimitOnRefill(t *testing.T) {
+	tb := newTokenBucket(2, time.Second)
+
+	// Simulate a very long idle period; tokens should cap at the limit
+	// rather than accumulating without bound.
+	tb.lastRefill = time.Now().Add(-time.Hour)
+
+	_, remaining, _ := tb.take()
+	if remaining != 1 {
+		t.Errorf("remaining = %d, want 1 (limit 2, minus the token just taken)", remaining)
+	}
+}
+
+func TestBehaviorMergeFillsZeroFieldsFromDefault(t *testing.T) {
+	def := Behavior{
+		LatencyMin:     time.Millisecond,
+		LatencyMax:     2 * time.Millisecond,
+		FailurePercent: 10,
+		FailureStatus:  503,
+		FailureType:    "overloaded",
+		FailureMessage: "too busy",
+		RateLimit:      &RateLimit{Limit: 5, Window: time.Second},
+		TimeoutPercent: 1,
+	}
+
+	merged := Behavior{}.merge(def)
+	if merged != def {
+		t.Errorf("merge of empty Behavior = %+v, want %+v", merged, def)
+	}
+}
+
+func TestBehaviorMergePrefersOwnFields(t *testing.T) {
+	def := Behavior{
+		LatencyMin:     time.Millisecond,
+		FailurePercent: 10,
+		FailureStatus:  503,
+		RateLimit:      &RateLimit{Limit: 5, Window: time.Second},
+		TimeoutPercent: 1,
+	}
+
+	own := Behavior{
+		LatencyMin:     5 * time.Millisecond,
+		FailurePercent: 50,
+		FailureStatus:  500,
+		RateLimit:      &RateLimit{Limit: 1, Window: time.Minute},
+		TimeoutPercent: 2,
+	}
+
+	merged := own.merge(def)
+	if merged != own {
+		t.Errorf("merge should not override already-set fields: got %+v, want %+v", merged, own)
+	}
+}
+
+func TestBehaviorMergeOverridePinsZeroValuedFields(t *testing.T) {
+	def := Behavior{
+		FailurePercent: 50,
+		FailureStatus:  503,
+		TimeoutPercent: 10,
+		RateLimit:      &RateLimit{Limit: 5, Window: time.Second},
+	}
+
+	reliable := Behavior{Override: true}
+
+	merged := reliable.merge(def)
+	if merged != reliable {
+		t.Errorf("an Override Behavior should merge to itself unchanged: got %+v, want %+v", merged, reliable)
+	}
+
+	if merged.FailurePercent != 0 || merged.TimeoutPercent != 0 || merged.RateLimit != nil {
+		t.Errorf("Override should pin explicit zero values instead of inheriting the default: got %+v", merged)
+	}
+}
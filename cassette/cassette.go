@@ -0,0 +1,70 @@
+// Package cassette sanitizes recorded HTTP interactions before MockLLM
+// persists them to disk as replayable mock fixtures.
+package cassette
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultHeaders lists the header names Sanitize always redacts, regardless
+// of the caller-supplied JSON paths.
+var defaultHeaders = []string{"Authorization", "x-api-key"}
+
+// Redacted is the placeholder value Sanitize writes in place of a scrubbed
+// field or header.
+const Redacted = "[REDACTED]"
+
+// Sanitize redacts sensitive headers and, optionally, configurable JSON
+// paths from a recorded request/response pair before it is written to a
+// cassette file. jsonPaths are dot-delimited paths into the decoded JSON
+// body, e.g. "choices.0.message.content".
+func Sanitize(headers http.Header, body map[string]any, jsonPaths []string) {
+	for _, h := range defaultHeaders {
+		if headers.Get(h) != "" {
+			headers.Set(h, Redacted)
+		}
+	}
+
+	for _, path := range jsonPaths {
+		redactPath(body, strings.Split(path, "."))
+	}
+}
+
+// redactPath walks value following segments, replacing the value found at
+// the final segment with Redacted. It understands both map keys and
+// numeric array indices, since decoded JSON bodies mix the two.
+func redactPath(value any, segments []string) {
+	if len(segments) == 0 {
+		return
+	}
+
+	segment := segments[0]
+
+	switch v := value.(type) {
+	case map[string]any:
+		if len(segments) == 1 {
+			if _, ok := v[segment]; ok {
+				v[segment] = Redacted
+			}
+			return
+		}
+
+		if next, ok := v[segment]; ok {
+			redactPath(next, segments[1:])
+		}
+	case []any:
+		idx, err := strconv.Atoi(segment)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return
+		}
+
+		if len(segments) == 1 {
+			v[idx] = Redacted
+			return
+		}
+
+		redactPath(v[idx], segments[1:])
+	}
+}
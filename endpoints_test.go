@@ -0,0 +1,267 @@
+package mockllm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+func startTestServer(t *testing.T, cfg Config) string {
+	t.Helper()
+
+	server := NewServer(cfg)
+
+	baseURL, err := server.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	t.Cleanup(func() { server.Stop() }) //nolint:errcheck
+
+	return baseURL
+}
+
+func postJSON(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+
+	return resp
+}
+
+func decodeJSON(t *testing.T, r io.Reader, v any) {
+	t.Helper()
+
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
+
+func TestEmbeddingsProviderHandle(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		Embeddings: []EmbeddingsMock{{
+			Name:     "embed",
+			Match:    EmbeddingsRequestMatch{MatchType: MatchTypeExact, Input: "hello"},
+			Response: openai.CreateEmbeddingResponse{Model: "text-embedding-3-small"},
+		}},
+	})
+
+	resp := postJSON(t, baseURL+"/v1/embeddings", `{"model":"text-embedding-3-small","input":"hello"}`)
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var got openai.CreateEmbeddingResponse
+	decodeJSON(t, resp.Body, &got)
+
+	if got.Model != "text-embedding-3-small" {
+		t.Errorf("Model = %q, want the mocked response", got.Model)
+	}
+}
+
+func TestEmbeddingsProviderNoMatch(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		Embeddings: []EmbeddingsMock{{
+			Name:  "embed",
+			Match: EmbeddingsRequestMatch{MatchType: MatchTypeExact, Input: "hello"},
+		}},
+	})
+
+	resp := postJSON(t, baseURL+"/v1/embeddings", `{"model":"text-embedding-3-small","input":"goodbye"}`)
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 for an unmatched input", resp.StatusCode)
+	}
+}
+
+func TestModerationProviderHandle(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		Moderations: []ModerationMock{{
+			Name:     "mod",
+			Match:    ModerationRequestMatch{MatchType: MatchTypeExact, Input: "hello"},
+			Response: openai.ModerationNewResponse{ID: "modr-1"},
+		}},
+	})
+
+	resp := postJSON(t, baseURL+"/v1/moderations", `{"input":"hello"}`)
+	defer resp.Body.Close() //nolint:errcheck
+
+	var got openai.ModerationNewResponse
+	decodeJSON(t, resp.Body, &got)
+
+	if got.ID != "modr-1" {
+		t.Errorf("ID = %q, want the mocked response", got.ID)
+	}
+}
+
+func TestImageProviderHandle(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		Images: []ImageMock{{
+			Name:     "image",
+			Match:    ImageRequestMatch{MatchType: MatchTypeExact, Prompt: "a cat"},
+			Response: openai.ImagesResponse{Created: 1234},
+		}},
+	})
+
+	resp := postJSON(t, baseURL+"/v1/images/generations", `{"prompt":"a cat"}`)
+	defer resp.Body.Close() //nolint:errcheck
+
+	var got openai.ImagesResponse
+	decodeJSON(t, resp.Body, &got)
+
+	if got.Created != 1234 {
+		t.Errorf("Created = %d, want the mocked response", got.Created)
+	}
+}
+
+func TestTranscriptionProviderHandle(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		Transcriptions: []TranscriptionMock{{
+			Name:     "transcribe",
+			Match:    TranscriptionRequestMatch{MatchType: MatchTypeExact, Filename: "audio.mp3"},
+			Response: openai.Transcription{Text: "hello world"},
+		}},
+	})
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	part, err := mw.CreateFormFile("file", "audio.mp3")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write([]byte("fake audio bytes")) //nolint:errcheck
+
+	if err := mw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	resp, err := http.Post(baseURL+"/v1/audio/transcriptions", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	var got openai.Transcription
+	decodeJSON(t, resp.Body, &got)
+
+	if got.Text != "hello world" {
+		t.Errorf("Text = %q, want the mocked response", got.Text)
+	}
+}
+
+func TestSpeechProviderHandle(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		Speech: []SpeechMock{{
+			Name:  "speech",
+			Match: SpeechRequestMatch{MatchType: MatchTypeExact, Input: "hello"},
+			Audio: []byte("fake mp3 bytes"),
+		}},
+	})
+
+	resp := postJSON(t, baseURL+"/v1/audio/speech", `{"input":"hello","model":"tts-1","voice":"alloy"}`)
+	defer resp.Body.Close() //nolint:errcheck
+
+	if ct := resp.Header.Get("Content-Type"); ct != "audio/mpeg" {
+		t.Errorf("Content-Type = %q, want the default audio/mpeg", ct)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	if string(body) != "fake mp3 bytes" {
+		t.Errorf("body = %q, want the mocked audio bytes", body)
+	}
+}
+
+func TestAnthropicCountTokensProviderHandle(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		AnthropicCountTokens: []AnthropicCountTokensMock{{
+			Name:     "count",
+			Match:    AnthropicCountTokensRequestMatch{MatchType: MatchTypeContains, Message: anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))},
+			Response: anthropic.MessageTokensCount{InputTokens: 7},
+		}},
+	})
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/v1/messages/count_tokens",
+		strings.NewReader(`{"model":"claude","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("x-api-key", "test")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	var got anthropic.MessageTokensCount
+	decodeJSON(t, resp.Body, &got)
+
+	if got.InputTokens != 7 {
+		t.Errorf("InputTokens = %d, want the mocked response", got.InputTokens)
+	}
+}
+
+func TestGoogleEmbedContentProviderHandle(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		GoogleEmbedContent: []GoogleEmbedContentMock{{
+			Name: "embed",
+			Match: GoogleRequestMatch{
+				MatchType: MatchTypeExact,
+				Content:   genai.Content{Role: "user", Parts: []*genai.Part{{Text: "hi"}}},
+			},
+			Response: genai.EmbedContentResponse{},
+		}},
+	})
+
+	resp := postJSON(t, baseURL+"/v1beta/models/text-embedding-004:embedContent",
+		`{"content":{"role":"user","parts":[{"text":"hi"}]}}`)
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d, want 200 for embedContent's singular \"content\" shape, body: %s", resp.StatusCode, body)
+	}
+}
+
+func TestGoogleCountTokensProviderHandle(t *testing.T) {
+	baseURL := startTestServer(t, Config{
+		GoogleCountTokens: []GoogleCountTokensMock{{
+			Name:     "count",
+			Match:    GoogleRequestMatch{MatchType: MatchTypeRegex, Regex: ".*"},
+			Response: genai.CountTokensResponse{TotalTokens: 3},
+		}},
+	})
+
+	resp := postJSON(t, baseURL+"/v1beta/models/gemini-pro:countTokens",
+		`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	defer resp.Body.Close() //nolint:errcheck
+
+	var got genai.CountTokensResponse
+	decodeJSON(t, resp.Body, &got)
+
+	if got.TotalTokens != 3 {
+		t.Errorf("TotalTokens = %d, want the mocked response", got.TotalTokens)
+	}
+}
@@ -7,21 +7,33 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/gorilla/mux"
 	"google.golang.org/genai"
 )
 
 // GoogleProvider handles Google request/response mocking
 type GoogleProvider struct {
-	mocks []GoogleMock
+	mocks           []GoogleMock
+	recorder        *RecordingProvider
+	tokenCounter    *TokenCounter
+	injector        *Injector
+	defaultBehavior Behavior
 }
 
 type GoogleRequestBody struct {
 	Contents []genai.Content `json:"contents"`
+	// SystemInstruction carries the request's system prompt, matched by SystemContains.
+	SystemInstruction *genai.Content `json:"systemInstruction,omitempty"`
+	// Tools carries the request's function declarations, matched by MatchTypeToolCall.
+	Tools []*genai.Tool `json:"tools,omitempty"`
 }
 
-// NewGoogleProvider creates a new Google GoogleProvider with the given mocks.
-func NewGoogleProvider(mocks []GoogleMock) *GoogleProvider {
-	return &GoogleProvider{mocks: mocks}
+// NewGoogleProvider creates a new Google GoogleProvider with the given
+// mocks. recorder may be nil, in which case unmatched requests are always a
+// 404. tokenCounter may be nil, in which case usage blocks are left as-is.
+// defaultBehavior fills any zero-valued field of a mock's own Behavior.
+func NewGoogleProvider(mocks []GoogleMock, recorder *RecordingProvider, tokenCounter *TokenCounter, injector *Injector, defaultBehavior Behavior) *GoogleProvider {
+	return &GoogleProvider{mocks: mocks, recorder: recorder, tokenCounter: tokenCounter, injector: injector, defaultBehavior: defaultBehavior}
 }
 
 // Handle processes a Google request.
@@ -36,13 +48,124 @@ func (p *GoogleProvider) Handle(w http.ResponseWriter, r *http.Request) {
 
 	mock := p.findMatchingMock(requestBody)
 	if mock == nil {
+		if p.recorder != nil && p.recorder.mode != RecordModeReplay && p.handleRecord(w, r, requestBody) {
+			return
+		}
+
 		http.Error(w, "No matching mock found", http.StatusNotFound)
 		return
 	}
 
+	p.tokenCounter.ApplyGoogle(&mock.Response, requestBody.Contents)
+
+	behavior := mock.Behavior.merge(p.defaultBehavior)
+	if p.injector.Apply(w, "google:"+mock.Name, "google", behavior) {
+		return
+	}
+
+	if ShouldTimeout(behavior.TimeoutPercent) {
+		DropConnection(w)
+		return
+	}
+
 	handleNonStreamingResponse(w, mock.Response)
 }
 
+// handleRecord forwards an unmatched request to the real upstream provider,
+// records the exchange, and proxies the upstream response back to the
+// client. It reports false, without forwarding, if the request has no
+// contents or can't be re-encoded.
+func (p *GoogleProvider) handleRecord(w http.ResponseWriter, r *http.Request, requestBody GoogleRequestBody) bool {
+	if len(requestBody.Contents) == 0 {
+		return false
+	}
+
+	reqBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return false
+	}
+
+	model := mux.Vars(r)["model"]
+
+	status, respBody, respHeader, err := p.recorder.Forward(r, fmt.Sprintf("/v1beta/models/%s:generateContent", model), reqBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to forward to upstream: %v", err), http.StatusBadGateway)
+		return true
+	}
+
+	lastContent, err := json.Marshal(requestBody.Contents[len(requestBody.Contents)-1])
+	if err != nil {
+		lastContent = []byte("{}")
+	}
+
+	if err := p.recorder.Record("google", "content", string(lastContent), r.Header.Clone(), respBody, nil); err != nil {
+		fmt.Printf("Failed to record cassette entry: %v\n", err)
+	}
+
+	if ct := respHeader.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	w.Write(respBody) //nolint:errcheck
+
+	return true
+}
+
+// HandleStream processes a Google :streamGenerateContent?alt=sse request,
+// emitting the matched mock's StreamChunks as "data: "-prefixed SSE frames,
+// the framing the genai SDK's stream parsing expects.
+func (p *GoogleProvider) HandleStream(w http.ResponseWriter, r *http.Request) {
+	var requestBody GoogleRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	mock := p.findMatchingMock(requestBody)
+	if mock == nil {
+		http.Error(w, "No matching mock found", http.StatusNotFound)
+		return
+	}
+
+	behavior := mock.Behavior.merge(p.defaultBehavior)
+
+	if len(mock.StreamChunks) == 0 {
+		p.tokenCounter.ApplyGoogle(&mock.Response, requestBody.Contents)
+
+		if p.injector.Apply(w, "google:"+mock.Name, "google", behavior) {
+			return
+		}
+
+		if ShouldTimeout(behavior.TimeoutPercent) {
+			DropConnection(w)
+			return
+		}
+
+		handleNonStreamingResponse(w, mock.Response)
+		return
+	}
+
+	if p.injector.Apply(w, "google:"+mock.Name, "google", behavior) {
+		return
+	}
+
+	frames := make([]string, 0, len(mock.StreamChunks))
+
+	for _, chunk := range mock.StreamChunks {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to encode chunk: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		frames = append(frames, fmt.Sprintf("data: %s\n\n", data))
+	}
+
+	handleStreamingResponse(w, frames, mock.ChunkDelay, behavior.TimeoutPercent)
+}
+
 // findMatchingMock finds the first mock that matches the request.
 func (p *GoogleProvider) findMatchingMock(request GoogleRequestBody) *GoogleMock {
 	for _, mock := range p.mocks {
@@ -54,37 +177,82 @@ func (p *GoogleProvider) findMatchingMock(request GoogleRequestBody) *GoogleMock
 	return nil
 }
 
-// requestsMatch checks if two requests are equivalent
+// requestsMatch checks if a request matches expected, per expected.MatchType.
 func (p *GoogleProvider) requestsMatch(expected GoogleRequestMatch, actual GoogleRequestBody) bool {
-	if len(actual.Contents) == 0 {
+	return googleContentMatches(expected, actual)
+}
+
+// googleContentMatches checks a Google request against expected, per
+// expected.MatchType. Shared by GoogleProvider and the
+// embedContent/countTokens providers, which all match on the same request
+// shape.
+func googleContentMatches(expected GoogleRequestMatch, actual GoogleRequestBody) bool {
+	if expected.SystemContains != "" && !googleSystemContains(actual, expected.SystemContains) {
 		return false
 	}
 
-	// return compareMessages(expected.MatchType, expected.Content, actual.Contents[len(actual.Contents)-1])
-
-	// Simple deep equal comparison for now
-	// In the future, we could add more sophisticated matching
 	switch expected.MatchType {
+	case MatchTypeAll:
+		for _, sub := range expected.Matchers {
+			if !googleContentMatches(sub, actual) {
+				return false
+			}
+		}
+
+		return true
+	case MatchTypeAny:
+		for _, sub := range expected.Matchers {
+			if googleContentMatches(sub, actual) {
+				return true
+			}
+		}
+
+		return false
+	case MatchTypeJSONPath:
+		return matchJSONPath(actual, expected.JSONPath, expected.Value, expected.Regex)
+	case MatchTypeRegex:
+		if len(actual.Contents) == 0 {
+			return false
+		}
+
+		return matchRegex(expected.Regex, textOf(actual.Contents[len(actual.Contents)-1]))
+	case MatchTypeToolCall:
+		return toolCallMatches(actual, expected.ToolName)
+	case MatchTypeExact, MatchTypeContains:
+		if len(actual.Contents) == 0 {
+			return false
+		}
+
+		return matchSingleContent(expected.MatchType, expected.Content, actual.Contents[len(actual.Contents)-1])
+	default:
+		return false
+	}
+}
+
+// matchSingleContent compares a single genai.Content against expected,
+// either by exact JSON equality or, for MatchTypeContains, by role plus a
+// substring match of the concatenated part text. Shared by googleContentMatches
+// (which compares the last of a Contents array) and
+// googleEmbedContentMatches (which compares embedContent's singular Content).
+func matchSingleContent(matchType MatchType, expected, actual genai.Content) bool {
+	switch matchType {
 	case MatchTypeExact:
-		lastMessage := actual.Contents[len(actual.Contents)-1]
-		// Check json is equal
-		jsonExpected, err := json.Marshal(expected.Content)
+		jsonExpected, err := json.Marshal(expected)
 		if err != nil {
 			return false
 		}
-		jsonActual, err := json.Marshal(lastMessage)
+		jsonActual, err := json.Marshal(actual)
 		if err != nil {
 			return false
 		}
 		return bytes.Equal(jsonExpected, jsonActual)
 	case MatchTypeContains:
-		lastMessage := actual.Contents[len(actual.Contents)-1]
-		if lastMessage.Role != expected.Content.Role {
+		if actual.Role != expected.Role {
 			return false
 		}
 
 		strExpected := ""
-		for i, part := range expected.Content.Parts {
+		for i, part := range expected.Parts {
 			if i > 0 {
 				strExpected += " "
 			}
@@ -92,7 +260,7 @@ func (p *GoogleProvider) requestsMatch(expected GoogleRequestMatch, actual Googl
 		}
 
 		strActual := ""
-		for i, part := range lastMessage.Parts {
+		for i, part := range actual.Parts {
 			if i > 0 {
 				strActual += " "
 			}
@@ -104,3 +272,113 @@ func (p *GoogleProvider) requestsMatch(expected GoogleRequestMatch, actual Googl
 		return false
 	}
 }
+
+// googleSystemContains reports whether the request's systemInstruction contains substr.
+func googleSystemContains(req GoogleRequestBody, substr string) bool {
+	if req.SystemInstruction == nil {
+		return false
+	}
+
+	return strings.Contains(textOf(req.SystemInstruction), substr)
+}
+
+// GoogleEmbedContentRequestBody is the request body for the Google
+// :embedContent endpoint, which sends a single "content" object rather than
+// the "contents" array used by generateContent/countTokens.
+type GoogleEmbedContentRequestBody struct {
+	Content genai.Content `json:"content"`
+}
+
+// GoogleEmbedContentProvider handles Google :embedContent request/response mocking.
+type GoogleEmbedContentProvider struct {
+	mocks []GoogleEmbedContentMock
+}
+
+// NewGoogleEmbedContentProvider creates a new GoogleEmbedContentProvider with the given mocks.
+func NewGoogleEmbedContentProvider(mocks []GoogleEmbedContentMock) *GoogleEmbedContentProvider {
+	return &GoogleEmbedContentProvider{mocks: mocks}
+}
+
+// Handle processes a Google :embedContent request.
+func (p *GoogleEmbedContentProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	var requestBody GoogleEmbedContentRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, mock := range p.mocks {
+		if googleEmbedContentMatches(mock.Match, requestBody) {
+			handleNonStreamingResponse(w, mock.Response)
+			return
+		}
+	}
+
+	http.Error(w, "No matching mock found", http.StatusNotFound)
+}
+
+// googleEmbedContentMatches checks an embedContent request's singular
+// Content against expected, per expected.MatchType.
+func googleEmbedContentMatches(expected GoogleRequestMatch, actual GoogleEmbedContentRequestBody) bool {
+	switch expected.MatchType {
+	case MatchTypeAll:
+		for _, sub := range expected.Matchers {
+			if !googleEmbedContentMatches(sub, actual) {
+				return false
+			}
+		}
+
+		return true
+	case MatchTypeAny:
+		for _, sub := range expected.Matchers {
+			if googleEmbedContentMatches(sub, actual) {
+				return true
+			}
+		}
+
+		return false
+	case MatchTypeJSONPath:
+		return matchJSONPath(actual, expected.JSONPath, expected.Value, expected.Regex)
+	case MatchTypeRegex:
+		return matchRegex(expected.Regex, textOf(actual.Content))
+	case MatchTypeToolCall:
+		return toolCallMatches(actual, expected.ToolName)
+	case MatchTypeExact, MatchTypeContains:
+		return matchSingleContent(expected.MatchType, expected.Content, actual.Content)
+	default:
+		return false
+	}
+}
+
+// GoogleCountTokensProvider handles Google :countTokens request/response mocking.
+type GoogleCountTokensProvider struct {
+	mocks []GoogleCountTokensMock
+}
+
+// NewGoogleCountTokensProvider creates a new GoogleCountTokensProvider with the given mocks.
+func NewGoogleCountTokensProvider(mocks []GoogleCountTokensMock) *GoogleCountTokensProvider {
+	return &GoogleCountTokensProvider{mocks: mocks}
+}
+
+// Handle processes a Google :countTokens request.
+func (p *GoogleCountTokensProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	var requestBody GoogleRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(requestBody.Contents) == 0 {
+		http.Error(w, "No matching mock found", http.StatusNotFound)
+		return
+	}
+
+	for _, mock := range p.mocks {
+		if googleContentMatches(mock.Match, requestBody) {
+			handleNonStreamingResponse(w, mock.Response)
+			return
+		}
+	}
+
+	http.Error(w, "No matching mock found", http.StatusNotFound)
+}
@@ -0,0 +1,316 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matchJSONPath evaluates path against req (via its generic JSON form) and
+// reports whether the result matches: against regex as a string, if set, or
+// otherwise an exact JSON comparison against expected.
+func matchJSONPath(req any, path string, expected any, regex string) bool {
+	root, ok := toGenericJSON(req)
+	if !ok {
+		return false
+	}
+
+	value, ok := evalJSONPath(root, path)
+	if !ok {
+		return false
+	}
+
+	if regex != "" {
+		return matchRegex(regex, fmt.Sprint(value))
+	}
+
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+
+	actualJSON, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(expectedJSON, actualJSON)
+}
+
+// matchRegex reports whether pattern matches text. An invalid pattern never matches.
+func matchRegex(pattern, text string) bool {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(text)
+}
+
+// toolCallMatches reports whether req declares a tool named name, or a
+// prior turn already called it, searching the specific locations each
+// provider uses for tool declarations and invocations: OpenAI's
+// "tools[*].function.name" and "messages[*].tool_calls[*].function.name",
+// Anthropic's "tools[*].name" and a "tool_use" content block's "name", and
+// Google's "tools[*].functionDeclarations[*].name" and a "functionCall"
+// part's "name". It deliberately does not search arbitrary fields named
+// "name" elsewhere in the payload, such as a tool's own JSON-schema
+// parameters.
+func toolCallMatches(req any, name string) bool {
+	root, ok := toGenericJSON(req)
+	if !ok {
+		return false
+	}
+
+	obj, ok := root.(map[string]any)
+	if !ok {
+		return false
+	}
+
+	return toolDeclared(obj["tools"], name) ||
+		toolCalledInMessages(obj["messages"], name) ||
+		toolCalledInContents(obj["contents"], name)
+}
+
+// toolDeclared reports whether tools (a request's top-level "tools" field)
+// declares a tool named name, under OpenAI's {"function": {"name": ...}},
+// Anthropic's flat {"name": ...}, or Google's {"functionDeclarations":
+// [{"name": ...}]} shape.
+func toolDeclared(tools any, name string) bool {
+	list, ok := tools.([]any)
+	if !ok {
+		return false
+	}
+
+	for _, t := range list {
+		tool, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if n, ok := tool["name"].(string); ok && n == name {
+			return true
+		}
+
+		if fn, ok := tool["function"].(map[string]any); ok {
+			if n, ok := fn["name"].(string); ok && n == name {
+				return true
+			}
+		}
+
+		decls, ok := tool["functionDeclarations"].([]any)
+		if !ok {
+			continue
+		}
+
+		for _, d := range decls {
+			decl, ok := d.(map[string]any)
+			if ok {
+				if n, ok := decl["name"].(string); ok && n == name {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// toolCalledInMessages reports whether messages (OpenAI's or Anthropic's
+// "messages" field) contains a prior invocation of a tool named name: an
+// OpenAI "tool_calls" entry or an Anthropic "tool_use" content block.
+func toolCalledInMessages(messages any, name string) bool {
+	list, ok := messages.([]any)
+	if !ok {
+		return false
+	}
+
+	for _, m := range list {
+		message, ok := m.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if calls, ok := message["tool_calls"].([]any); ok {
+			for _, c := range calls {
+				call, ok := c.(map[string]any)
+				if !ok {
+					continue
+				}
+
+				fn, ok := call["function"].(map[string]any)
+				if ok {
+					if n, ok := fn["name"].(string); ok && n == name {
+						return true
+					}
+				}
+			}
+		}
+
+		blocks, ok := message["content"].([]any)
+		if !ok {
+			continue
+		}
+
+		for _, b := range blocks {
+			block, ok := b.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			if t, _ := block["type"].(string); t != "tool_use" {
+				continue
+			}
+
+			if n, ok := block["name"].(string); ok && n == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// toolCalledInContents reports whether contents (Google's "contents" field)
+// contains a prior "functionCall" part named name.
+func toolCalledInContents(contents any, name string) bool {
+	list, ok := contents.([]any)
+	if !ok {
+		return false
+	}
+
+	for _, c := range list {
+		content, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		parts, ok := content["parts"].([]any)
+		if !ok {
+			continue
+		}
+
+		for _, p := range parts {
+			part, ok := p.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			fc, ok := part["functionCall"].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			if n, ok := fc["name"].(string); ok && n == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// toGenericJSON round-trips v through encoding/json to get a tree of
+// map[string]any/[]any/string/float64/bool/nil, the shape evalJSONPath and
+// toolCallMatches operate on.
+func toGenericJSON(v any) (any, bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, false
+	}
+
+	return root, true
+}
+
+// evalJSONPath evaluates a small subset of JSONPath: a leading "$", then
+// dot-separated field names, each optionally followed by one or more
+// "[n]" indices (negative indices count from the end of the array). It
+// does not support wildcards, filters, or recursive descent.
+func evalJSONPath(root any, path string) (any, bool) {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+
+	if path == "" {
+		return root, true
+	}
+
+	current := root
+
+	for _, segment := range strings.Split(path, ".") {
+		name, indices, err := parseJSONPathSegment(segment)
+		if err != nil {
+			return nil, false
+		}
+
+		if name != "" {
+			m, ok := current.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+
+			current, ok = m[name]
+			if !ok {
+				return nil, false
+			}
+		}
+
+		for _, idx := range indices {
+			s, ok := current.([]any)
+			if !ok {
+				return nil, false
+			}
+
+			if idx < 0 {
+				idx += len(s)
+			}
+
+			if idx < 0 || idx >= len(s) {
+				return nil, false
+			}
+
+			current = s[idx]
+		}
+	}
+
+	return current, true
+}
+
+// parseJSONPathSegment splits a JSONPath segment like "messages[-1][0]"
+// into its field name and any bracketed indices, in order.
+func parseJSONPathSegment(segment string) (string, []int, error) {
+	name := segment
+
+	var indices []int
+
+	for {
+		open := strings.IndexByte(name, '[')
+		if open == -1 {
+			break
+		}
+
+		closeOffset := strings.IndexByte(name[open:], ']')
+		if closeOffset == -1 {
+			return "", nil, fmt.Errorf("unterminated [ in %q", segment)
+		}
+
+		closeIdx := open + closeOffset
+
+		idx, err := strconv.Atoi(name[open+1 : closeIdx])
+		if err != nil {
+			return "", nil, err
+		}
+
+		indices = append(indices, idx)
+		name = name[:open] + name[closeIdx+1:]
+	}
+
+	return name, indices, nil
+}
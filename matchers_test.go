@@ -0,0 +1,227 @@
+package mockllm
+
+import "testing"
+
+func TestEvalJSONPath(t *testing.T) {
+	root, ok := toGenericJSON(map[string]any{
+		"messages": []any{
+			map[string]any{"role": "user", "content": "first"},
+			map[string]any{"role": "assistant", "content": "second"},
+		},
+	})
+	if !ok {
+		t.Fatal("toGenericJSON failed")
+	}
+
+	if got, ok := evalJSONPath(root, "$"); !ok || got == nil {
+		t.Fatalf("evalJSONPath($) = %v, %v, want the root value", got, ok)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+		ok   bool
+	}{
+		{"index", "$.messages[0].content", "first", true},
+		{"negative index", "$.messages[-1].content", "second", true},
+		{"missing field", "$.nope", "", false},
+		{"out of range", "$.messages[5]", "", false},
+		{"index into non-array", "$.messages[0].content[0]", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := evalJSONPath(root, tt.path)
+			if ok != tt.ok {
+				t.Fatalf("evalJSONPath(%q) ok = %v, want %v", tt.path, ok, tt.ok)
+			}
+
+			if ok && got != tt.want {
+				t.Fatalf("evalJSONPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathSegment(t *testing.T) {
+	tests := []struct {
+		segment     string
+		wantName    string
+		wantIndices []int
+		wantErr     bool
+	}{
+		{"messages", "messages", nil, false},
+		{"messages[0]", "messages", []int{0}, false},
+		{"messages[-1][2]", "messages", []int{-1, 2}, false},
+		{"messages[bad]", "", nil, true},
+		{"messages[0", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		name, indices, err := parseJSONPathSegment(tt.segment)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseJSONPathSegment(%q) err = %v, wantErr %v", tt.segment, err, tt.wantErr)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if name != tt.wantName || len(indices) != len(tt.wantIndices) {
+			t.Fatalf("parseJSONPathSegment(%q) = %q, %v, want %q, %v", tt.segment, name, indices, tt.wantName, tt.wantIndices)
+		}
+
+		for i, idx := range indices {
+			if idx != tt.wantIndices[i] {
+				t.Fatalf("parseJSONPathSegment(%q) indices = %v, want %v", tt.segment, indices, tt.wantIndices)
+			}
+		}
+	}
+}
+
+func TestMatchJSONPath(t *testing.T) {
+	req := map[string]any{"messages": []any{map[string]any{"role": "user", "content": "hello world"}}}
+
+	if !matchJSONPath(req, "$.messages[0].role", "user", "") {
+		t.Error("expected exact match on role")
+	}
+
+	if matchJSONPath(req, "$.messages[0].role", "assistant", "") {
+		t.Error("did not expect match on mismatched role")
+	}
+
+	if !matchJSONPath(req, "$.messages[0].content", nil, "^hello") {
+		t.Error("expected regex match on content")
+	}
+
+	if matchJSONPath(req, "$.missing", "x", "") {
+		t.Error("did not expect match on missing path")
+	}
+}
+
+func TestMatchRegex(t *testing.T) {
+	if !matchRegex("^foo", "foobar") {
+		t.Error("expected match")
+	}
+
+	if matchRegex("^foo", "barfoo") {
+		t.Error("did not expect match")
+	}
+
+	if matchRegex("(", "anything") {
+		t.Error("invalid pattern should never match")
+	}
+}
+
+func TestToolCallMatchesOpenAI(t *testing.T) {
+	declared := map[string]any{
+		"tools": []any{
+			map[string]any{"type": "function", "function": map[string]any{"name": "get_weather"}},
+		},
+	}
+
+	if !toolCallMatches(declared, "get_weather") {
+		t.Error("expected declared OpenAI tool to match")
+	}
+
+	called := map[string]any{
+		"messages": []any{
+			map[string]any{
+				"role": "assistant",
+				"tool_calls": []any{
+					map[string]any{"type": "function", "function": map[string]any{"name": "get_weather"}},
+				},
+			},
+		},
+	}
+
+	if !toolCallMatches(called, "get_weather") {
+		t.Error("expected prior OpenAI tool_calls invocation to match")
+	}
+
+	if toolCallMatches(called, "get_time") {
+		t.Error("did not expect a match for an uncalled tool")
+	}
+}
+
+func TestToolCallMatchesAnthropic(t *testing.T) {
+	declared := map[string]any{
+		"tools": []any{map[string]any{"name": "get_weather", "description": "..."}},
+	}
+
+	if !toolCallMatches(declared, "get_weather") {
+		t.Error("expected declared Anthropic tool to match")
+	}
+
+	used := map[string]any{
+		"messages": []any{
+			map[string]any{
+				"role": "assistant",
+				"content": []any{
+					map[string]any{"type": "tool_use", "name": "get_weather"},
+				},
+			},
+		},
+	}
+
+	if !toolCallMatches(used, "get_weather") {
+		t.Error("expected a tool_use content block to match")
+	}
+}
+
+func TestToolCallMatchesGoogle(t *testing.T) {
+	declared := map[string]any{
+		"tools": []any{
+			map[string]any{"functionDeclarations": []any{map[string]any{"name": "get_weather"}}},
+		},
+	}
+
+	if !toolCallMatches(declared, "get_weather") {
+		t.Error("expected declared Google functionDeclaration to match")
+	}
+
+	called := map[string]any{
+		"contents": []any{
+			map[string]any{
+				"role": "model",
+				"parts": []any{
+					map[string]any{"functionCall": map[string]any{"name": "get_weather"}},
+				},
+			},
+		},
+	}
+
+	if !toolCallMatches(called, "get_weather") {
+		t.Error("expected a functionCall part to match")
+	}
+}
+
+func TestToolCallMatchesIgnoresUnrelatedNameFields(t *testing.T) {
+	// A tool's own JSON-schema parameters may contain an unrelated field
+	// literally called "name" (e.g. a "name" property the tool accepts as an
+	// argument). That must not false-positive a tool-call match.
+	req := map[string]any{
+		"tools": []any{
+			map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name": "get_weather",
+					"parameters": map[string]any{
+						"properties": map[string]any{
+							"name": map[string]any{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if toolCallMatches(req, "string") {
+		t.Error("did not expect a match against an unrelated nested \"name\" field")
+	}
+
+	if !toolCallMatches(req, "get_weather") {
+		t.Error("expected the actual declared tool to still match")
+	}
+}
@@ -3,26 +3,41 @@ package mockllm
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/openai/openai-go"
 )
 
 // Provider handles OpenAI request/response mocking
 type OpenAIProvider struct {
-	mocks []OpenAIMock
+	mocks           []OpenAIMock
+	recorder        *RecordingProvider
+	tokenCounter    *TokenCounter
+	injector        *Injector
+	defaultBehavior Behavior
 }
 
-// NewOpenAIProvider creates a new OpenAI OpenAIProvider with the given mocks
-func NewOpenAIProvider(mocks []OpenAIMock) *OpenAIProvider {
-	return &OpenAIProvider{mocks: mocks}
+// NewOpenAIProvider creates a new OpenAI OpenAIProvider with the given
+// mocks. recorder may be nil, in which case unmatched requests are always a
+// 404. tokenCounter may be nil, in which case usage blocks are left as-is.
+// defaultBehavior fills any zero-valued field of a mock's own Behavior.
+func NewOpenAIProvider(mocks []OpenAIMock, recorder *RecordingProvider, tokenCounter *TokenCounter, injector *Injector, defaultBehavior Behavior) *OpenAIProvider {
+	return &OpenAIProvider{mocks: mocks, recorder: recorder, tokenCounter: tokenCounter, injector: injector, defaultBehavior: defaultBehavior}
 }
 
 // Handle processes an OpenAI chat completion request
 func (p *OpenAIProvider) Handle(w http.ResponseWriter, r *http.Request) {
 	// Parse the incoming request into SDK type
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	var requestBody openai.ChatCompletionNewParams
-	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+	if err := json.Unmarshal(body, &requestBody); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
 		return
 	}
@@ -30,14 +45,100 @@ func (p *OpenAIProvider) Handle(w http.ResponseWriter, r *http.Request) {
 	// Find a matching mock
 	mock := p.findMatchingMock(requestBody)
 	if mock == nil {
+		if p.recorder != nil && p.recorder.mode != RecordModeReplay && p.handleRecord(w, r, requestBody) {
+			return
+		}
+
 		http.Error(w, "No matching mock found.", http.StatusNotFound)
 		return
 	}
 
+	p.tokenCounter.ApplyOpenAI(&mock.Response, requestBody.Messages)
+
+	behavior := mock.Behavior.merge(p.defaultBehavior)
+	if p.injector.Apply(w, "openai:"+mock.Name, "openai", behavior) {
+		return
+	}
+
+	if requestWantsStream(body) && len(mock.StreamChunks) > 0 {
+		p.handleStream(w, *mock, behavior.TimeoutPercent)
+		return
+	}
+
+	if ShouldTimeout(behavior.TimeoutPercent) {
+		DropConnection(w)
+		return
+	}
+
 	// Return the response
 	handleNonStreamingResponse(w, mock.Response)
 }
 
+// handleStream emits the mock's StreamChunks as OpenAI chat completion chunk
+// SSE frames, terminated by the documented "data: [DONE]" sentinel.
+func (p *OpenAIProvider) handleStream(w http.ResponseWriter, mock OpenAIMock, timeoutPercent float64) {
+	frames := make([]string, 0, len(mock.StreamChunks)+1)
+
+	for _, chunk := range mock.StreamChunks {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to encode chunk: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		frames = append(frames, fmt.Sprintf("data: %s\n\n", data))
+	}
+
+	frames = append(frames, "data: [DONE]\n\n")
+
+	handleStreamingResponse(w, frames, mock.ChunkDelay, timeoutPercent)
+}
+
+// handleRecord forwards an unmatched request to the real upstream provider,
+// records the exchange, and proxies the upstream response back to the
+// client. It reports false, without forwarding, if the request has no
+// messages or can't be re-encoded.
+func (p *OpenAIProvider) handleRecord(w http.ResponseWriter, r *http.Request, requestBody openai.ChatCompletionNewParams) bool {
+	if len(requestBody.Messages) == 0 {
+		return false
+	}
+
+	reqBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return false
+	}
+
+	status, respBody, respHeader, err := p.recorder.Forward(r, "/v1/chat/completions", reqBody)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to forward to upstream: %v", err), http.StatusBadGateway)
+		return true
+	}
+
+	lastMessage, err := json.Marshal(requestBody.Messages[len(requestBody.Messages)-1])
+	if err != nil {
+		lastMessage = []byte("{}")
+	}
+
+	streamChunks := splitSSEFrames(respBody, respHeader.Get("Content-Type"))
+	if len(streamChunks) > 0 {
+		if err := p.recorder.Record("openai", "message", string(lastMessage), r.Header.Clone(), nil, streamChunks); err != nil {
+			fmt.Printf("Failed to record cassette entry: %v\n", err)
+		}
+	} else if err := p.recorder.Record("openai", "message", string(lastMessage), r.Header.Clone(), respBody, nil); err != nil {
+		fmt.Printf("Failed to record cassette entry: %v\n", err)
+	}
+
+	if ct := respHeader.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	w.Write(respBody) //nolint:errcheck
+
+	return true
+}
+
 // findMatchingMock finds the first mock that matches the request
 func (p *OpenAIProvider) findMatchingMock(request openai.ChatCompletionNewParams) *OpenAIMock {
 	for _, mock := range p.mocks {
@@ -48,11 +149,220 @@ func (p *OpenAIProvider) findMatchingMock(request openai.ChatCompletionNewParams
 	return nil
 }
 
-// requestsMatch checks if two requests are equivalent
+// requestsMatch checks if a request matches expected, per expected.MatchType.
 func (p *OpenAIProvider) requestsMatch(expected OpenAIRequestMatch, actual openai.ChatCompletionNewParams) bool {
-	if len(actual.Messages) == 0 {
+	if expected.SystemContains != "" && !openAISystemContains(actual, expected.SystemContains) {
 		return false
 	}
 
-	return compareMessages(expected.MatchType, expected.Message, actual.Messages[len(actual.Messages)-1])
+	switch expected.MatchType {
+	case MatchTypeAll:
+		for _, sub := range expected.Matchers {
+			if !p.requestsMatch(sub, actual) {
+				return false
+			}
+		}
+
+		return true
+	case MatchTypeAny:
+		for _, sub := range expected.Matchers {
+			if p.requestsMatch(sub, actual) {
+				return true
+			}
+		}
+
+		return false
+	case MatchTypeJSONPath:
+		return matchJSONPath(actual, expected.JSONPath, expected.Value, expected.Regex)
+	case MatchTypeRegex:
+		if len(actual.Messages) == 0 {
+			return false
+		}
+
+		return matchRegex(expected.Regex, textOf(actual.Messages[len(actual.Messages)-1]))
+	case MatchTypeToolCall:
+		return toolCallMatches(actual, expected.ToolName)
+	default:
+		if len(actual.Messages) == 0 {
+			return false
+		}
+
+		return compareMessages(expected.MatchType, expected.Message, actual.Messages[len(actual.Messages)-1])
+	}
+}
+
+// openAISystemContains reports whether any "system" role message in the
+// request contains substr.
+func openAISystemContains(req openai.ChatCompletionNewParams, substr string) bool {
+	for _, msg := range req.Messages {
+		decoded, ok := toGenericJSON(msg)
+		if !ok {
+			continue
+		}
+
+		m, ok := decoded.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if role, _ := m["role"].(string); role == "system" && strings.Contains(textOf(m), substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EmbeddingsProvider handles OpenAI embeddings request/response mocking.
+type EmbeddingsProvider struct {
+	mocks []EmbeddingsMock
+}
+
+// NewEmbeddingsProvider creates a new EmbeddingsProvider with the given mocks.
+func NewEmbeddingsProvider(mocks []EmbeddingsMock) *EmbeddingsProvider {
+	return &EmbeddingsProvider{mocks: mocks}
+}
+
+// Handle processes an OpenAI embeddings request.
+func (p *EmbeddingsProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	var requestBody openai.EmbeddingNewParams
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, mock := range p.mocks {
+		if compareMessages(mock.Match.MatchType, mock.Match.Input, requestBody.Input) {
+			handleNonStreamingResponse(w, mock.Response)
+			return
+		}
+	}
+
+	http.Error(w, "No matching mock found.", http.StatusNotFound)
+}
+
+// ModerationProvider handles OpenAI moderation request/response mocking.
+type ModerationProvider struct {
+	mocks []ModerationMock
+}
+
+// NewModerationProvider creates a new ModerationProvider with the given mocks.
+func NewModerationProvider(mocks []ModerationMock) *ModerationProvider {
+	return &ModerationProvider{mocks: mocks}
+}
+
+// Handle processes an OpenAI moderation request.
+func (p *ModerationProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	var requestBody openai.ModerationNewParams
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, mock := range p.mocks {
+		if compareMessages(mock.Match.MatchType, mock.Match.Input, requestBody.Input) {
+			handleNonStreamingResponse(w, mock.Response)
+			return
+		}
+	}
+
+	http.Error(w, "No matching mock found.", http.StatusNotFound)
+}
+
+// ImageProvider handles OpenAI image generation request/response mocking.
+type ImageProvider struct {
+	mocks []ImageMock
+}
+
+// NewImageProvider creates a new ImageProvider with the given mocks.
+func NewImageProvider(mocks []ImageMock) *ImageProvider {
+	return &ImageProvider{mocks: mocks}
+}
+
+// Handle processes an OpenAI image generation request.
+func (p *ImageProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	var requestBody openai.ImageGenerateParams
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, mock := range p.mocks {
+		if compareMessages(mock.Match.MatchType, mock.Match.Prompt, requestBody.Prompt) {
+			handleNonStreamingResponse(w, mock.Response)
+			return
+		}
+	}
+
+	http.Error(w, "No matching mock found.", http.StatusNotFound)
+}
+
+// TranscriptionProvider handles OpenAI audio transcription request/response
+// mocking. Requests are multipart, so mocks are matched on the uploaded
+// file's name rather than any decoded SDK request type.
+type TranscriptionProvider struct {
+	mocks []TranscriptionMock
+}
+
+// NewTranscriptionProvider creates a new TranscriptionProvider with the given mocks.
+func NewTranscriptionProvider(mocks []TranscriptionMock) *TranscriptionProvider {
+	return &TranscriptionProvider{mocks: mocks}
+}
+
+// Handle processes an OpenAI audio transcription request.
+func (p *TranscriptionProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid multipart form: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var filename string
+	if files := r.MultipartForm.File["file"]; len(files) > 0 {
+		filename = files[0].Filename
+	}
+
+	for _, mock := range p.mocks {
+		if compareMessages(mock.Match.MatchType, mock.Match.Filename, filename) {
+			handleNonStreamingResponse(w, mock.Response)
+			return
+		}
+	}
+
+	http.Error(w, "No matching mock found.", http.StatusNotFound)
+}
+
+// SpeechProvider handles OpenAI text-to-speech request/response mocking.
+type SpeechProvider struct {
+	mocks []SpeechMock
+}
+
+// NewSpeechProvider creates a new SpeechProvider with the given mocks.
+func NewSpeechProvider(mocks []SpeechMock) *SpeechProvider {
+	return &SpeechProvider{mocks: mocks}
+}
+
+// Handle processes an OpenAI text-to-speech request, returning raw audio
+// bytes rather than JSON.
+func (p *SpeechProvider) Handle(w http.ResponseWriter, r *http.Request) {
+	var requestBody openai.AudioSpeechNewParams
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for _, mock := range p.mocks {
+		if compareMessages(mock.Match.MatchType, mock.Match.Input, requestBody.Input) {
+			mimeType := mock.MimeType
+			if mimeType == "" {
+				mimeType = "audio/mpeg"
+			}
+
+			w.Header().Set("Content-Type", mimeType)
+			w.WriteHeader(http.StatusOK)
+			w.Write(mock.Audio) //nolint:errcheck
+			return
+		}
+	}
+
+	http.Error(w, "No matching mock found.", http.StatusNotFound)
 }
@@ -0,0 +1,237 @@
+package mockllm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dhaifley/mockllm/cassette"
+)
+
+// RecordMode selects how a provider behaves when no configured mock matches
+// an incoming request.
+type RecordMode string
+
+const (
+	// RecordModeRecord always forwards unmatched requests upstream and
+	// records the exchange into the cassette file.
+	RecordModeRecord RecordMode = "record"
+	// RecordModeReplay only serves mocks loaded from the cassette file; an
+	// unmatched request is a 404, same as with hand-authored mocks.
+	RecordModeReplay RecordMode = "replay"
+	// RecordModeAuto replays a cassette match when one exists and records a
+	// new one otherwise.
+	RecordModeAuto RecordMode = "auto"
+)
+
+// RecordingProvider forwards requests unmatched by any configured mock to a
+// real upstream LLM provider and persists the request/response pair to a
+// cassette file in the same schema as hand-authored mocks, so MockLLM can
+// double as a test fixture generator.
+type RecordingProvider struct {
+	mode          RecordMode
+	upstreamURL   string
+	apiKey        string
+	cassettePath  string
+	sanitizePaths []string
+	client        *http.Client
+}
+
+// NewRecordingProvider creates a RecordingProvider that forwards to
+// upstreamURL using apiKey, recording matches into cassettePath.
+func NewRecordingProvider(mode RecordMode, upstreamURL, apiKey, cassettePath string, sanitizePaths []string) *RecordingProvider {
+	return &RecordingProvider{
+		mode:          mode,
+		upstreamURL:   upstreamURL,
+		apiKey:        apiKey,
+		cassettePath:  cassettePath,
+		sanitizePaths: sanitizePaths,
+		client:        &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Forward replays r's method/headers/body against path on the real upstream
+// provider and returns the raw response.
+func (rp *RecordingProvider) Forward(r *http.Request, path string, reqBody []byte) (status int, respBody []byte, respHeader http.Header, err error) {
+	upstreamReq, err := http.NewRequestWithContext(r.Context(), r.Method, strings.TrimSuffix(rp.upstreamURL, "/")+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to build upstream request: %w", err)
+	}
+
+	upstreamReq.Header = r.Header.Clone()
+	if rp.apiKey != "" {
+		if upstreamReq.Header.Get("Authorization") != "" {
+			upstreamReq.Header.Set("Authorization", "Bearer "+rp.apiKey)
+		}
+		if upstreamReq.Header.Get("x-api-key") != "" {
+			upstreamReq.Header.Set("x-api-key", rp.apiKey)
+		}
+	}
+
+	resp, err := rp.client.Do(upstreamReq)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to call upstream: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to read upstream response: %w", err)
+	}
+
+	return resp.StatusCode, body, resp.Header, nil
+}
+
+// Record appends a sanitized request/response pair to the cassette file
+// under the given provider section ("openai", "anthropic", "google"),
+// creating the file if it doesn't exist yet.
+func (rp *RecordingProvider) Record(provider, matchKey, matchValue string, reqHeaders http.Header, response json.RawMessage, streamChunks []json.RawMessage) error {
+	cas, err := loadRawCassette(rp.cassettePath)
+	if err != nil {
+		return err
+	}
+
+	respBody := map[string]any{}
+	if len(response) > 0 {
+		if err := json.Unmarshal(response, &respBody); err != nil {
+			return fmt.Errorf("failed to decode response for sanitization: %w", err)
+		}
+	}
+
+	cassette.Sanitize(reqHeaders, respBody, rp.sanitizePaths)
+
+	sanitized, err := json.Marshal(respBody)
+	if err != nil {
+		return fmt.Errorf("failed to re-encode sanitized response: %w", err)
+	}
+
+	entry := map[string]any{
+		"name": fmt.Sprintf("%s-recorded-%d", provider, len(cas[provider])+1),
+		"match": map[string]any{
+			"match_type": string(MatchTypeExact),
+			matchKey:     json.RawMessage(matchValue),
+		},
+		"response": json.RawMessage(sanitized),
+	}
+
+	if len(streamChunks) > 0 {
+		sanitizedChunks, err := sanitizeStreamChunks(streamChunks, rp.sanitizePaths)
+		if err != nil {
+			return err
+		}
+
+		entry["stream_chunks"] = sanitizedChunks
+	}
+
+	cas[provider] = append(cas[provider], entry)
+
+	return writeRawCassette(rp.cassettePath, cas)
+}
+
+// sanitizeStreamChunks decodes each streamed frame, applies the same
+// sanitizePaths redaction used on non-streaming responses, and re-encodes
+// it. Streaming is the default transport for most real chat clients, so
+// skipping this would leave sensitive data in the common case unredacted.
+func sanitizeStreamChunks(streamChunks []json.RawMessage, sanitizePaths []string) ([]json.RawMessage, error) {
+	sanitized := make([]json.RawMessage, len(streamChunks))
+
+	for i, chunk := range streamChunks {
+		body := map[string]any{}
+		if err := json.Unmarshal(chunk, &body); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk for sanitization: %w", err)
+		}
+
+		cassette.Sanitize(nil, body, sanitizePaths)
+
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to re-encode sanitized stream chunk: %w", err)
+		}
+
+		sanitized[i] = data
+	}
+
+	return sanitized, nil
+}
+
+// splitSSEFrames extracts the JSON payloads from an SSE response body's
+// "data:" lines, skipping the "[DONE]" sentinel and any "event:" lines. It
+// returns nil unless contentType indicates an event stream.
+func splitSSEFrames(body []byte, contentType string) []json.RawMessage {
+	if !strings.Contains(contentType, "text/event-stream") {
+		return nil
+	}
+
+	var frames []json.RawMessage
+
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+
+		data := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		if string(data) == "[DONE]" || len(data) == 0 {
+			continue
+		}
+
+		frames = append(frames, json.RawMessage(append([]byte(nil), data...)))
+	}
+
+	return frames
+}
+
+func loadRawCassette(path string) (map[string][]map[string]any, error) {
+	cas := map[string][]map[string]any{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cas, nil
+		}
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &cas); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette: %w", err)
+		}
+	}
+
+	return cas, nil
+}
+
+func writeRawCassette(path string, cas map[string][]map[string]any) error {
+	data, err := json.MarshalIndent(cas, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// LoadConfigFromCassette reads a cassette file written by RecordingProvider
+// and decodes it directly into a Config, since cassettes share the same
+// on-disk schema as hand-authored mock lists.
+func LoadConfigFromCassette(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse cassette: %w", err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,183 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordingProviderForward(t *testing.T) {
+	var gotAuth string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body) //nolint:errcheck
+	}))
+	defer upstream.Close()
+
+	rp := NewRecordingProvider(RecordModeRecord, upstream.URL, "secret-key", filepath.Join(t.TempDir(), "cassette.json"), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer placeholder")
+
+	status, respBody, _, err := rp.Forward(req, "/v1/chat/completions", []byte(`{"hello":"world"}`))
+	if err != nil {
+		t.Fatalf("Forward: %v", err)
+	}
+
+	if status != http.StatusOK {
+		t.Errorf("status = %d, want 200", status)
+	}
+
+	if string(respBody) != `{"hello":"world"}` {
+		t.Errorf("respBody = %s, want the body echoed back", respBody)
+	}
+
+	if gotAuth != "Bearer secret-key" {
+		t.Errorf("upstream Authorization = %q, want the configured API key substituted in", gotAuth)
+	}
+}
+
+func TestRecordingProviderRecordSanitizesResponseAndStreamChunks(t *testing.T) {
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+
+	rp := NewRecordingProvider(RecordModeRecord, "http://upstream.example", "", cassettePath, []string{"choices.0.message.content", "choices.0.delta.content"})
+
+	response := json.RawMessage(`{"choices":[{"message":{"content":"secret answer"}}]}`)
+	if err := rp.Record("openai", "message", `{"role":"user"}`, http.Header{}, response, nil); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	streamChunks := []json.RawMessage{
+		json.RawMessage(`{"choices":[{"delta":{"content":"secret chunk"}}]}`),
+	}
+	if err := rp.Record("openai", "message", `{"role":"user"}`, http.Header{}, nil, streamChunks); err != nil {
+		t.Fatalf("Record (streaming): %v", err)
+	}
+
+	cas, err := loadRawCassette(cassettePath)
+	if err != nil {
+		t.Fatalf("loadRawCassette: %v", err)
+	}
+
+	entries := cas["openai"]
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	respJSON, err := json.Marshal(entries[0]["response"])
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	if string(respJSON) == string(response) {
+		t.Error("expected the configured JSON path to be sanitized in the non-streaming response")
+	}
+
+	chunksJSON, err := json.Marshal(entries[1]["stream_chunks"])
+	if err != nil {
+		t.Fatalf("marshal stream_chunks: %v", err)
+	}
+	if string(chunksJSON) == string(mustMarshal(t, streamChunks)) {
+		t.Error("expected stream chunks to be sanitized the same way as a non-streaming response")
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	return data
+}
+
+func TestSplitSSEFrames(t *testing.T) {
+	body := []byte("data: {\"a\":1}\n\ndata: {\"a\":2}\n\ndata: [DONE]\n\n")
+
+	frames := splitSSEFrames(body, "text/event-stream; charset=utf-8")
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2 (the [DONE] sentinel should be skipped)", len(frames))
+	}
+
+	if string(frames[0]) != `{"a":1}` || string(frames[1]) != `{"a":2}` {
+		t.Errorf("frames = %v, want [{\"a\":1} {\"a\":2}]", frames)
+	}
+
+	if frames := splitSSEFrames(body, "application/json"); frames != nil {
+		t.Errorf("expected no frames for a non-event-stream Content-Type, got %v", frames)
+	}
+}
+
+func TestLoadRawCassetteRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	cas := map[string][]map[string]any{
+		"openai": {{"name": "entry-1"}},
+	}
+
+	if err := writeRawCassette(path, cas); err != nil {
+		t.Fatalf("writeRawCassette: %v", err)
+	}
+
+	got, err := loadRawCassette(path)
+	if err != nil {
+		t.Fatalf("loadRawCassette: %v", err)
+	}
+
+	if len(got["openai"]) != 1 || got["openai"][0]["name"] != "entry-1" {
+		t.Errorf("got %+v, want the written cassette back", got)
+	}
+}
+
+func TestLoadRawCassetteMissingFileReturnsEmpty(t *testing.T) {
+	cas, err := loadRawCassette(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	if err != nil {
+		t.Fatalf("loadRawCassette: %v", err)
+	}
+
+	if len(cas) != 0 {
+		t.Errorf("got %+v, want an empty cassette for a missing file", cas)
+	}
+}
+
+func TestLoadConfigFromCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	cas := map[string]any{
+		"openai": []map[string]any{
+			{
+				"name":     "openai-recorded-1",
+				"match":    map[string]any{"match_type": "exact", "message": map[string]any{"role": "user", "content": "hi"}},
+				"response": map[string]any{"id": "chatcmpl-1"},
+			},
+		},
+	}
+
+	data, err := json.Marshal(cas)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg, err := LoadConfigFromCassette(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFromCassette: %v", err)
+	}
+
+	if len(cfg.OpenAI) != 1 || cfg.OpenAI[0].Name != "openai-recorded-1" {
+		t.Errorf("got %+v, want one recorded OpenAI mock", cfg.OpenAI)
+	}
+}
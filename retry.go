@@ -0,0 +1,37 @@
+package mockllm
+
+import (
+	"context"
+	"time"
+)
+
+// RetryWithBackoff calls fn until it succeeds, ctx is done, or attempts is
+// exhausted, doubling the delay between attempts from initial up to max.
+func RetryWithBackoff(ctx context.Context, attempts int, initial, max time.Duration, fn func() error) error {
+	delay := initial
+
+	var err error
+
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if i == attempts-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > max {
+			delay = max
+		}
+	}
+
+	return err
+}
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
+	"math/rand"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -15,49 +17,100 @@ import (
 
 // Server is the main mock LLM server
 type Server struct {
-	config            Config
-	openaiProvider    *OpenAIProvider
-	anthropicProvider *AnthropicProvider
-	googleProvider    *GoogleProvider
-	router            *mux.Router
-	listener          net.Listener
+	config                       Config
+	openaiProvider               *OpenAIProvider
+	anthropicProvider            *AnthropicProvider
+	googleProvider               *GoogleProvider
+	embeddingsProvider           *EmbeddingsProvider
+	moderationProvider           *ModerationProvider
+	imageProvider                *ImageProvider
+	transcriptionProvider        *TranscriptionProvider
+	speechProvider               *SpeechProvider
+	anthropicCountTokensProvider *AnthropicCountTokensProvider
+	googleEmbedContentProvider   *GoogleEmbedContentProvider
+	googleCountTokensProvider    *GoogleCountTokensProvider
+	router                       *mux.Router
+	listener                     net.Listener
 }
 
 // NewServer creates a new mock LLM server with the given config
 func NewServer(config Config) *Server {
+	// In "replay"/"auto" mode, merge in any mocks already recorded to the
+	// cassette file so they're served like hand-authored mocks.
+	if config.RecordMode == RecordModeReplay || config.RecordMode == RecordModeAuto {
+		if config.CassettePath != "" {
+			if cas, err := LoadConfigFromCassette(config.CassettePath); err == nil {
+				config.OpenAI = append(config.OpenAI, cas.OpenAI...)
+				config.Anthropic = append(config.Anthropic, cas.Anthropic...)
+				config.Google = append(config.Google, cas.Google...)
+			}
+		}
+	}
+
+	var recorder *RecordingProvider
+	if config.RecordMode != "" {
+		recorder = NewRecordingProvider(
+			config.RecordMode,
+			config.UpstreamBaseURL,
+			os.Getenv(config.UpstreamAPIKeyEnv),
+			config.CassettePath,
+			config.SanitizeJSONPaths,
+		)
+	}
+
+	tokenCounter := NewTokenCounter(config.UsageMode, NewCharsPerTokenCounter(config.CharsPerToken))
+	injector := NewInjector()
+
 	// Convert config to provider mocks
 	var openaiMocks []OpenAIMock
 	for _, mock := range config.OpenAI {
 		openaiMocks = append(openaiMocks, OpenAIMock{
-			Name:     mock.Name,
-			Match:    mock.Match,
-			Response: mock.Response,
+			Name:         mock.Name,
+			Match:        mock.Match,
+			Response:     mock.Response,
+			StreamChunks: mock.StreamChunks,
+			ChunkDelay:   mock.ChunkDelay,
+			Behavior:     mock.Behavior,
 		})
 	}
 
 	var anthropicMocks []AnthropicMock
 	for _, mock := range config.Anthropic {
 		anthropicMocks = append(anthropicMocks, AnthropicMock{
-			Name:     mock.Name,
-			Match:    mock.Match,
-			Response: mock.Response,
+			Name:         mock.Name,
+			Match:        mock.Match,
+			Response:     mock.Response,
+			StreamChunks: mock.StreamChunks,
+			ChunkDelay:   mock.ChunkDelay,
+			Behavior:     mock.Behavior,
 		})
 	}
 
 	var googleMocks []GoogleMock
 	for _, mock := range config.Google {
 		googleMocks = append(googleMocks, GoogleMock{
-			Name:     mock.Name,
-			Match:    mock.Match,
-			Response: mock.Response,
+			Name:         mock.Name,
+			Match:        mock.Match,
+			Response:     mock.Response,
+			StreamChunks: mock.StreamChunks,
+			ChunkDelay:   mock.ChunkDelay,
+			Behavior:     mock.Behavior,
 		})
 	}
 
 	return &Server{
-		config:            config,
-		openaiProvider:    NewOpenAIProvider(openaiMocks),
-		anthropicProvider: NewAnthropicProvider(anthropicMocks),
-		googleProvider:    NewGoogleProvider(googleMocks),
+		config:                       config,
+		openaiProvider:               NewOpenAIProvider(openaiMocks, recorder, tokenCounter, injector, config.DefaultBehavior),
+		anthropicProvider:            NewAnthropicProvider(anthropicMocks, recorder, tokenCounter, injector, config.DefaultBehavior),
+		googleProvider:               NewGoogleProvider(googleMocks, recorder, tokenCounter, injector, config.DefaultBehavior),
+		embeddingsProvider:           NewEmbeddingsProvider(config.Embeddings),
+		moderationProvider:           NewModerationProvider(config.Moderations),
+		imageProvider:                NewImageProvider(config.Images),
+		transcriptionProvider:        NewTranscriptionProvider(config.Transcriptions),
+		speechProvider:               NewSpeechProvider(config.Speech),
+		anthropicCountTokensProvider: NewAnthropicCountTokensProvider(config.AnthropicCountTokens),
+		googleEmbedContentProvider:   NewGoogleEmbedContentProvider(config.GoogleEmbedContent),
+		googleCountTokensProvider:    NewGoogleCountTokensProvider(config.GoogleCountTokens),
 	}
 }
 
@@ -134,9 +187,20 @@ func (s *Server) setupRoutes() {
 
 	// Anthropic Messages API
 	r.HandleFunc("/v1/messages", s.anthropicProvider.Handle).Methods("POST")
+	r.HandleFunc("/v1/messages/count_tokens", s.anthropicCountTokensProvider.Handle).Methods("POST")
 
 	// Google Generate Content API
 	r.HandleFunc("/v1beta/models/{model}:generateContent", s.googleProvider.Handle).Methods("POST")
+	r.HandleFunc("/v1beta/models/{model}:streamGenerateContent", s.googleProvider.HandleStream).Methods("POST")
+	r.HandleFunc("/v1beta/models/{model}:embedContent", s.googleEmbedContentProvider.Handle).Methods("POST")
+	r.HandleFunc("/v1beta/models/{model}:countTokens", s.googleCountTokensProvider.Handle).Methods("POST")
+
+	// OpenAI-compatible embeddings, moderation, image, and audio APIs
+	r.HandleFunc("/v1/embeddings", s.embeddingsProvider.Handle).Methods("POST")
+	r.HandleFunc("/v1/moderations", s.moderationProvider.Handle).Methods("POST")
+	r.HandleFunc("/v1/images/generations", s.imageProvider.Handle).Methods("POST")
+	r.HandleFunc("/v1/audio/transcriptions", s.transcriptionProvider.Handle).Methods("POST")
+	r.HandleFunc("/v1/audio/speech", s.speechProvider.Handle).Methods("POST")
 
 	// Debug route
 	r.NotFoundHandler = http.HandlerFunc(s.handleNotFound)
@@ -317,6 +381,24 @@ func compareMessages(matchType MatchType, expected any, actual any) bool {
 	}
 }
 
+// requestWantsStream reports whether the raw request body sets "stream":
+// true. Neither openai.ChatCompletionNewParams nor
+// anthropic.MessageNewParams has a Stream field of its own — both SDKs
+// select streaming by which client method is called, not a request-body
+// flag — so callers that need this peek at the raw JSON rather than the
+// decoded params struct.
+func requestWantsStream(body []byte) bool {
+	var peek struct {
+		Stream bool `json:"stream"`
+	}
+
+	if err := json.Unmarshal(body, &peek); err != nil {
+		return false
+	}
+
+	return peek.Stream
+}
+
 // handleNonStreamingResponse sends a JSON response.
 func handleNonStreamingResponse(w http.ResponseWriter, response any) {
 	w.Header().Set("Content-Type", "application/json")
@@ -326,3 +408,45 @@ func handleNonStreamingResponse(w http.ResponseWriter, response any) {
 		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
 	}
 }
+
+// handleStreamingResponse writes pre-rendered SSE/NDJSON frames to w, flushing
+// after each one so clients observe them incrementally. chunkDelay, if
+// non-zero, is slept between frames so tests can exercise streaming timing.
+// timeoutPercent, if its roll hits, drops the connection after a random
+// number of frames instead of completing the stream, so clients can be
+// tested against a response that times out partway through.
+func handleStreamingResponse(w http.ResponseWriter, frames []string, chunkDelay time.Duration, timeoutPercent float64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	dropAt := -1
+	if len(frames) > 0 && ShouldTimeout(timeoutPercent) {
+		dropAt = rand.Intn(len(frames))
+	}
+
+	for i, frame := range frames {
+		if i > 0 && chunkDelay > 0 {
+			time.Sleep(chunkDelay)
+		}
+
+		if i == dropAt {
+			DropConnection(w)
+			return
+		}
+
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+}
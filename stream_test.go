@@ -0,0 +1,197 @@
+package mockllm
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+// readSSELines posts body to url and returns the non-empty lines of the
+// response, in order.
+func readSSELines(t *testing.T, url, body string) []string {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s: status %d", url, resp.StatusCode)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	var lines []string
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+func TestOpenAIStreamingEmitsSSEFrames(t *testing.T) {
+	cfg := Config{
+		OpenAI: []OpenAIMock{{
+			Name:  "stream",
+			Match: OpenAIRequestMatch{MatchType: MatchTypeRegex, Regex: ".*"},
+			StreamChunks: []openai.ChatCompletionChunk{
+				{ID: "chunk-1"},
+			},
+		}},
+	}
+
+	server := NewServer(cfg)
+
+	baseURL, err := server.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer server.Stop() //nolint:errcheck
+
+	lines := readSSELines(t, baseURL+"/v1/chat/completions",
+		`{"model":"gpt-4","messages":[{"role":"user","content":"hi"}],"stream":true}`)
+
+	if len(lines) == 0 {
+		t.Fatal("expected at least one SSE line")
+	}
+
+	if !strings.HasPrefix(lines[0], "data: ") {
+		t.Errorf("first line = %q, want a \"data: \"-prefixed frame", lines[0])
+	}
+
+	if last := lines[len(lines)-1]; last != "data: [DONE]" {
+		t.Errorf("last line = %q, want the \"data: [DONE]\" sentinel", last)
+	}
+}
+
+func TestOpenAINonStreamingRequestIsNotTreatedAsStream(t *testing.T) {
+	cfg := Config{
+		OpenAI: []OpenAIMock{{
+			Name:         "stream",
+			Match:        OpenAIRequestMatch{MatchType: MatchTypeRegex, Regex: ".*"},
+			StreamChunks: []openai.ChatCompletionChunk{{ID: "chunk-1"}},
+		}},
+	}
+
+	server := NewServer(cfg)
+
+	baseURL, err := server.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer server.Stop() //nolint:errcheck
+
+	resp, err := http.Post(baseURL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model":"gpt-4","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json for a non-streaming request", ct)
+	}
+}
+
+func TestAnthropicStreamingEmitsSSEFrames(t *testing.T) {
+	cfg := Config{
+		Anthropic: []AnthropicMock{{
+			Name:  "stream",
+			Match: AnthropicRequestMatch{MatchType: MatchTypeRegex, Regex: ".*"},
+			StreamChunks: []anthropic.MessageStreamEventUnion{
+				{Type: "message_start"},
+				{Type: "content_block_delta", Delta: anthropic.MessageStreamEventUnionDelta{Text: "hi"}},
+				{Type: "message_stop"},
+			},
+		}},
+	}
+
+	server := NewServer(cfg)
+
+	baseURL, err := server.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer server.Stop() //nolint:errcheck
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/v1/messages",
+		strings.NewReader(`{"model":"claude","messages":[{"role":"user","content":"hi"}],"stream":true,"max_tokens":64}`))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("x-api-key", "test")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer httpResp.Body.Close() //nolint:errcheck
+
+	if httpResp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", httpResp.StatusCode)
+	}
+
+	var eventLines, dataLines int
+
+	scanner := bufio.NewScanner(httpResp.Body)
+	for scanner.Scan() {
+		switch line := scanner.Text(); {
+		case strings.HasPrefix(line, "event: "):
+			eventLines++
+		case strings.HasPrefix(line, "data: "):
+			dataLines++
+		}
+	}
+
+	if eventLines != 3 || dataLines != 3 {
+		t.Errorf("got %d event lines and %d data lines, want 3 of each", eventLines, dataLines)
+	}
+}
+
+func TestGoogleStreamingEmitsSSEFrames(t *testing.T) {
+	cfg := Config{
+		Google: []GoogleMock{{
+			Name:         "stream",
+			Match:        GoogleRequestMatch{MatchType: MatchTypeRegex, Regex: ".*"},
+			StreamChunks: []genai.GenerateContentResponse{{}, {}},
+		}},
+	}
+
+	server := NewServer(cfg)
+
+	baseURL, err := server.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer server.Stop() //nolint:errcheck
+
+	lines := readSSELines(t, baseURL+"/v1beta/models/gemini-pro:streamGenerateContent",
+		`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+
+	if len(lines) != 2 {
+		t.Fatalf("got %d SSE lines, want 2 (one per stream chunk)", len(lines))
+	}
+
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "data: ") {
+			t.Errorf("line = %q, want a \"data: \"-prefixed SSE frame (the genai SDK's streamGenerateContent?alt=sse parser requires it)", line)
+		}
+	}
+}
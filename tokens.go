@@ -0,0 +1,166 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+// UsageMode controls how a TokenCounter treats the usage block already
+// present on a mocked response.
+type UsageMode string
+
+const (
+	// UsageModeStatic leaves a mock's usage block untouched.
+	UsageModeStatic UsageMode = "static"
+	// UsageModeComputed always overwrites the usage block with computed counts.
+	UsageModeComputed UsageMode = "computed"
+	// UsageModeAugment only fills in fields that are zero in the mock.
+	UsageModeAugment UsageMode = "augment"
+)
+
+// Counter computes an approximate token count for a piece of text.
+type Counter interface {
+	Count(text string) int
+}
+
+// wordSplitRe splits text into runs of letters/digits and individual
+// punctuation characters, so short words aren't undercounted relative to a
+// pure chars-per-token average.
+var wordSplitRe = regexp.MustCompile(`[\p{L}\p{N}]+|[^\s\p{L}\p{N}]`)
+
+// CharsPerTokenCounter approximates BPE tokenization with a fixed
+// characters-per-token ratio applied per word/punctuation run.
+type CharsPerTokenCounter struct {
+	CharsPerToken float64
+}
+
+// NewCharsPerTokenCounter creates a CharsPerTokenCounter. charsPerToken <= 0
+// defaults to 4, a common rule-of-thumb ratio for English text tokenized by
+// a BPE vocabulary like tiktoken's.
+func NewCharsPerTokenCounter(charsPerToken float64) *CharsPerTokenCounter {
+	if charsPerToken <= 0 {
+		charsPerToken = 4
+	}
+
+	return &CharsPerTokenCounter{CharsPerToken: charsPerToken}
+}
+
+// Count implements Counter.
+func (c *CharsPerTokenCounter) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	total := 0
+	for _, word := range wordSplitRe.FindAllString(text, -1) {
+		n := int(math.Ceil(float64(len(word)) / c.CharsPerToken))
+		if n < 1 {
+			n = 1
+		}
+		total += n
+	}
+
+	return total
+}
+
+// TokenCounter rewrites the usage block on mocked responses so client
+// retry/budgeting code exercises the same accounting paths it would against
+// a real provider.
+type TokenCounter struct {
+	mode    UsageMode
+	counter Counter
+}
+
+// NewTokenCounter creates a TokenCounter. A nil counter defaults to
+// NewCharsPerTokenCounter(0).
+func NewTokenCounter(mode UsageMode, counter Counter) *TokenCounter {
+	if counter == nil {
+		counter = NewCharsPerTokenCounter(0)
+	}
+
+	return &TokenCounter{mode: mode, counter: counter}
+}
+
+// textOf flattens v to its JSON representation so prompt/completion token
+// counts can be approximated without unpacking every message/content union
+// variant by hand.
+func textOf(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+// fields returns the prompt and completion token counts to use for an
+// existing (prompt, completion) pair, honoring UsageMode.
+func (tc *TokenCounter) fields(promptText, completionText string, existingPrompt, existingCompletion int64) (prompt, completion int64) {
+	prompt, completion = existingPrompt, existingCompletion
+
+	switch tc.mode {
+	case UsageModeComputed:
+		prompt = int64(tc.counter.Count(promptText))
+		completion = int64(tc.counter.Count(completionText))
+	case UsageModeAugment:
+		if prompt == 0 {
+			prompt = int64(tc.counter.Count(promptText))
+		}
+		if completion == 0 {
+			completion = int64(tc.counter.Count(completionText))
+		}
+	}
+
+	return prompt, completion
+}
+
+// ApplyOpenAI rewrites resp.Usage according to the configured UsageMode.
+func (tc *TokenCounter) ApplyOpenAI(resp *openai.ChatCompletion, messages []openai.ChatCompletionMessageParamUnion) {
+	if tc == nil || tc.mode == UsageModeStatic || tc.mode == "" {
+		return
+	}
+
+	prompt, completion := tc.fields(textOf(messages), textOf(resp.Choices), resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+
+	resp.Usage.PromptTokens = prompt
+	resp.Usage.CompletionTokens = completion
+	resp.Usage.TotalTokens = prompt + completion
+}
+
+// ApplyAnthropic rewrites resp.Usage according to the configured UsageMode.
+func (tc *TokenCounter) ApplyAnthropic(resp *anthropic.Message, messages []anthropic.MessageParam) {
+	if tc == nil || tc.mode == UsageModeStatic || tc.mode == "" {
+		return
+	}
+
+	prompt, completion := tc.fields(textOf(messages), textOf(resp.Content), resp.Usage.InputTokens, resp.Usage.OutputTokens)
+
+	resp.Usage.InputTokens = prompt
+	resp.Usage.OutputTokens = completion
+}
+
+// ApplyGoogle rewrites resp.UsageMetadata according to the configured
+// UsageMode.
+func (tc *TokenCounter) ApplyGoogle(resp *genai.GenerateContentResponse, contents []genai.Content) {
+	if tc == nil || tc.mode == UsageModeStatic || tc.mode == "" {
+		return
+	}
+
+	if resp.UsageMetadata == nil {
+		resp.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{}
+	}
+
+	prompt, completion := tc.fields(
+		textOf(contents), textOf(resp.Candidates),
+		int64(resp.UsageMetadata.PromptTokenCount), int64(resp.UsageMetadata.CandidatesTokenCount),
+	)
+
+	resp.UsageMetadata.PromptTokenCount = int32(prompt)
+	resp.UsageMetadata.CandidatesTokenCount = int32(completion)
+	resp.UsageMetadata.TotalTokenCount = int32(prompt + completion)
+}
@@ -0,0 +1,134 @@
+package mockllm
+
+import (
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+func TestCharsPerTokenCounterCount(t *testing.T) {
+	c := NewCharsPerTokenCounter(4)
+
+	if got := c.Count(""); got != 0 {
+		t.Errorf("Count(\"\") = %d, want 0", got)
+	}
+
+	if got := c.Count("hello"); got != 2 {
+		t.Errorf("Count(\"hello\") = %d, want 2 (ceil(5/4))", got)
+	}
+
+	// Punctuation counts as its own run, so it's never undercounted to 0.
+	if got := c.Count("hi!"); got != 2 {
+		t.Errorf("Count(\"hi!\") = %d, want 2 (1 for \"hi\", 1 for \"!\")", got)
+	}
+}
+
+func TestNewCharsPerTokenCounterDefaultsRatio(t *testing.T) {
+	c := NewCharsPerTokenCounter(0)
+	if c.CharsPerToken != 4 {
+		t.Errorf("CharsPerToken = %v, want the default of 4", c.CharsPerToken)
+	}
+}
+
+func TestTokenCounterFieldsHonorsUsageMode(t *testing.T) {
+	tc := NewTokenCounter(UsageModeStatic, NewCharsPerTokenCounter(4))
+
+	prompt, completion := tc.fields("twelve characters", "twelve characters", 5, 5)
+	if prompt != 5 || completion != 5 {
+		t.Errorf("static mode should leave existing counts untouched, got (%d, %d)", prompt, completion)
+	}
+
+	tc = NewTokenCounter(UsageModeComputed, NewCharsPerTokenCounter(4))
+	prompt, completion = tc.fields("hello", "hello world", 5, 5)
+	if prompt == 5 || completion == 5 {
+		t.Errorf("computed mode should overwrite existing counts, got (%d, %d)", prompt, completion)
+	}
+
+	tc = NewTokenCounter(UsageModeAugment, NewCharsPerTokenCounter(4))
+	prompt, completion = tc.fields("hello", "hello world", 5, 0)
+	if prompt != 5 {
+		t.Errorf("augment mode should leave a nonzero existing prompt count alone, got %d", prompt)
+	}
+	if completion == 0 {
+		t.Error("augment mode should fill a zero existing completion count")
+	}
+}
+
+func TestApplyOpenAIComputesUsage(t *testing.T) {
+	tc := NewTokenCounter(UsageModeComputed, NewCharsPerTokenCounter(4))
+
+	resp := openai.ChatCompletion{}
+	messages := []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hello there")}
+
+	tc.ApplyOpenAI(&resp, messages)
+
+	if resp.Usage.PromptTokens == 0 {
+		t.Error("expected PromptTokens to be computed")
+	}
+
+	if resp.Usage.TotalTokens != resp.Usage.PromptTokens+resp.Usage.CompletionTokens {
+		t.Errorf("TotalTokens = %d, want PromptTokens + CompletionTokens", resp.Usage.TotalTokens)
+	}
+}
+
+func TestApplyOpenAIStaticModeLeavesUsageAlone(t *testing.T) {
+	tc := NewTokenCounter(UsageModeStatic, NewCharsPerTokenCounter(4))
+
+	resp := openai.ChatCompletion{}
+	resp.Usage.PromptTokens = 42
+
+	tc.ApplyOpenAI(&resp, []openai.ChatCompletionMessageParamUnion{openai.UserMessage("hello")})
+
+	if resp.Usage.PromptTokens != 42 {
+		t.Errorf("PromptTokens = %d, want untouched 42", resp.Usage.PromptTokens)
+	}
+}
+
+func TestApplyAnthropicComputesUsage(t *testing.T) {
+	tc := NewTokenCounter(UsageModeComputed, NewCharsPerTokenCounter(4))
+
+	resp := anthropic.Message{}
+	messages := []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hello there"))}
+
+	tc.ApplyAnthropic(&resp, messages)
+
+	if resp.Usage.InputTokens == 0 {
+		t.Error("expected InputTokens to be computed")
+	}
+}
+
+func TestApplyGoogleComputesUsage(t *testing.T) {
+	tc := NewTokenCounter(UsageModeComputed, NewCharsPerTokenCounter(4))
+
+	resp := genai.GenerateContentResponse{}
+	contents := []genai.Content{{Role: "user", Parts: []*genai.Part{{Text: "hello there"}}}}
+
+	tc.ApplyGoogle(&resp, contents)
+
+	if resp.UsageMetadata == nil {
+		t.Fatal("expected UsageMetadata to be allocated")
+	}
+
+	if resp.UsageMetadata.PromptTokenCount == 0 {
+		t.Error("expected PromptTokenCount to be computed")
+	}
+
+	if resp.UsageMetadata.TotalTokenCount != resp.UsageMetadata.PromptTokenCount+resp.UsageMetadata.CandidatesTokenCount {
+		t.Errorf("TotalTokenCount = %d, want PromptTokenCount + CandidatesTokenCount", resp.UsageMetadata.TotalTokenCount)
+	}
+}
+
+func TestApplyNilTokenCounterIsNoop(t *testing.T) {
+	var tc *TokenCounter
+
+	resp := openai.ChatCompletion{}
+	resp.Usage.PromptTokens = 7
+
+	tc.ApplyOpenAI(&resp, nil)
+
+	if resp.Usage.PromptTokens != 7 {
+		t.Errorf("PromptTokens = %d, want untouched 7 when TokenCounter is nil", resp.Usage.PromptTokens)
+	}
+}
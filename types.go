@@ -1,6 +1,8 @@
 package mockllm
 
 import (
+	"time"
+
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/openai/openai-go"
 	"google.golang.org/genai"
@@ -15,6 +17,55 @@ type Config struct {
 	Google    []GoogleMock    `json:"google,omitempty"`
 	// ListenAddr is the address to listen on. Defaults to 0.0.0.0:0 (any IP address and ephemeral port)
 	ListenAddr string `json:"listen_addr,omitempty"`
+
+	// RecordMode enables recording and/or replaying requests against a real
+	// upstream provider. One of "record", "replay", or "auto". Empty
+	// disables recording and MockLLM behaves as a plain mock server.
+	RecordMode RecordMode `json:"record_mode,omitempty"`
+	// UpstreamBaseURL is the base URL of the real provider to forward
+	// unmatched requests to in "record" and "auto" modes.
+	UpstreamBaseURL string `json:"upstream_base_url,omitempty"`
+	// UpstreamAPIKeyEnv names the environment variable holding the API key
+	// used to authenticate forwarded requests.
+	UpstreamAPIKeyEnv string `json:"upstream_api_key_env,omitempty"`
+	// CassettePath is the JSON file recorded interactions are loaded from
+	// and appended to.
+	CassettePath string `json:"cassette_path,omitempty"`
+	// SanitizeJSONPaths are additional dot-delimited JSON paths, beyond the
+	// default Authorization/x-api-key headers, scrubbed from recordings
+	// before they're written to CassettePath.
+	SanitizeJSONPaths []string `json:"sanitize_json_paths,omitempty"`
+
+	// Embeddings mocks the OpenAI-compatible /v1/embeddings endpoint.
+	Embeddings []EmbeddingsMock `json:"embeddings,omitempty"`
+	// Moderations mocks the OpenAI-compatible /v1/moderations endpoint.
+	Moderations []ModerationMock `json:"moderations,omitempty"`
+	// Images mocks the OpenAI-compatible /v1/images/generations endpoint.
+	Images []ImageMock `json:"images,omitempty"`
+	// Transcriptions mocks the OpenAI-compatible /v1/audio/transcriptions endpoint.
+	Transcriptions []TranscriptionMock `json:"transcriptions,omitempty"`
+	// Speech mocks the OpenAI-compatible /v1/audio/speech endpoint.
+	Speech []SpeechMock `json:"speech,omitempty"`
+	// AnthropicCountTokens mocks the Anthropic /v1/messages/count_tokens endpoint.
+	AnthropicCountTokens []AnthropicCountTokensMock `json:"anthropic_count_tokens,omitempty"`
+	// GoogleEmbedContent mocks the Google :embedContent endpoint.
+	GoogleEmbedContent []GoogleEmbedContentMock `json:"google_embed_content,omitempty"`
+	// GoogleCountTokens mocks the Google :countTokens endpoint.
+	GoogleCountTokens []GoogleCountTokensMock `json:"google_count_tokens,omitempty"`
+
+	// UsageMode controls how response usage blocks are rewritten before
+	// they're sent: "static" (use whatever's in the mock, the default),
+	// "computed" (always overwrite), or "augment" (fill only zero fields).
+	UsageMode UsageMode `json:"usage_mode,omitempty"`
+	// CharsPerToken sets the characters-per-token ratio used by the default
+	// token counter when UsageMode isn't "static". Defaults to 4.
+	CharsPerToken float64 `json:"chars_per_token,omitempty"`
+
+	// DefaultBehavior fills every zero-valued field of each OpenAI/Anthropic/
+	// Google mock's Behavior (unless that Behavior sets Override), so latency,
+	// failure, rate-limit, and timeout injection can be enabled server-wide
+	// without repeating the same block on each mock.
+	DefaultBehavior Behavior `json:"default_behavior,omitempty"`
 }
 
 type MatchType string
@@ -22,11 +73,39 @@ type MatchType string
 const (
 	MatchTypeExact    MatchType = "exact"
 	MatchTypeContains MatchType = "contains"
+	// MatchTypeJSONPath evaluates JSONPath against the full decoded request
+	// and compares the result to Value (or, if Regex is set, matches the
+	// result's string form against it).
+	MatchTypeJSONPath MatchType = "json_path"
+	// MatchTypeRegex matches Regex against the flattened text of the last message.
+	MatchTypeRegex MatchType = "regex"
+	// MatchTypeToolCall matches when the request declares a tool named
+	// ToolName, or a prior turn already called it.
+	MatchTypeToolCall MatchType = "tool_call"
+	// MatchTypeAll matches when every sub-matcher in Matchers matches.
+	MatchTypeAll MatchType = "all"
+	// MatchTypeAny matches when at least one sub-matcher in Matchers matches.
+	MatchTypeAny MatchType = "any"
 )
 
 type OpenAIRequestMatch struct {
 	MatchType MatchType                              `json:"match_type"`
 	Message   openai.ChatCompletionMessageParamUnion `json:"message"`
+	// JSONPath is the expression evaluated by MatchTypeJSONPath, e.g. "$.messages[-1].content".
+	JSONPath string `json:"json_path,omitempty"`
+	// Regex is the pattern used by MatchTypeRegex, and optionally by
+	// MatchTypeJSONPath to match the path's result as a string instead of
+	// an exact comparison against Value.
+	Regex string `json:"regex,omitempty"`
+	// Value is the expected result compared against MatchTypeJSONPath's evaluation.
+	Value any `json:"value,omitempty"`
+	// ToolName is the tool/function name matched by MatchTypeToolCall.
+	ToolName string `json:"tool_name,omitempty"`
+	// SystemContains, if set, additionally requires the request's system
+	// prompt (the "system" role message) to contain this substring.
+	SystemContains string `json:"system_contains,omitempty"`
+	// Matchers holds the sub-matchers for MatchTypeAll/MatchTypeAny.
+	Matchers []OpenAIRequestMatch `json:"matchers,omitempty"`
 }
 
 // OpenAIMock maps an OpenAI request to a response using official SDK types
@@ -34,11 +113,32 @@ type OpenAIMock struct {
 	Name     string                `json:"name"`     // identifier for this mock
 	Match    OpenAIRequestMatch    `json:"match"`    // Match type and value
 	Response openai.ChatCompletion `json:"response"` // OpenAI response to return (ChatCompletion or ChatCompletionChunk)
+	// StreamChunks, if set, are emitted as SSE frames instead of Response when the request has "stream": true.
+	StreamChunks []openai.ChatCompletionChunk `json:"stream_chunks,omitempty"`
+	// ChunkDelay is an optional delay applied between streamed chunks.
+	ChunkDelay time.Duration `json:"chunk_delay,omitempty"`
+	// Behavior's nonzero fields override Config.DefaultBehavior for this mock.
+	Behavior Behavior `json:"behavior,omitempty"`
 }
 
 type AnthropicRequestMatch struct {
 	MatchType MatchType              `json:"match_type"`
 	Message   anthropic.MessageParam `json:"message"`
+	// JSONPath is the expression evaluated by MatchTypeJSONPath, e.g. "$.messages[-1].content".
+	JSONPath string `json:"json_path,omitempty"`
+	// Regex is the pattern used by MatchTypeRegex, and optionally by
+	// MatchTypeJSONPath to match the path's result as a string instead of
+	// an exact comparison against Value.
+	Regex string `json:"regex,omitempty"`
+	// Value is the expected result compared against MatchTypeJSONPath's evaluation.
+	Value any `json:"value,omitempty"`
+	// ToolName is the tool name matched by MatchTypeToolCall.
+	ToolName string `json:"tool_name,omitempty"`
+	// SystemContains, if set, additionally requires the request's top-level
+	// "system" field to contain this substring.
+	SystemContains string `json:"system_contains,omitempty"`
+	// Matchers holds the sub-matchers for MatchTypeAll/MatchTypeAny.
+	Matchers []AnthropicRequestMatch `json:"matchers,omitempty"`
 }
 
 // AnthropicMock maps an Anthropic request to a response using official SDK types
@@ -46,11 +146,36 @@ type AnthropicMock struct {
 	Name     string                `json:"name"`     // identifier for this mock
 	Match    AnthropicRequestMatch `json:"match"`    // Match type and value
 	Response anthropic.Message     `json:"response"` // Anthropic response to return (Message or streaming event)
+	// StreamChunks, if set, are emitted as the documented Anthropic SSE event sequence
+	// (message_start, content_block_start, content_block_delta, content_block_stop,
+	// message_delta, message_stop) instead of Response when the request has "stream": true.
+	StreamChunks []anthropic.MessageStreamEventUnion `json:"stream_chunks,omitempty"`
+	// ChunkDelay is an optional delay applied between streamed events.
+	ChunkDelay time.Duration `json:"chunk_delay,omitempty"`
+	// Behavior's nonzero fields override Config.DefaultBehavior for this mock.
+	Behavior Behavior `json:"behavior,omitempty"`
 }
 
 type GoogleRequestMatch struct {
 	MatchType MatchType     `json:"match_type"`
 	Content   genai.Content `json:"content"`
+	// JSONPath is the expression evaluated by MatchTypeJSONPath, e.g. "$.contents[-1].parts[0].text".
+	// Only the request's contents, systemInstruction, and tools fields are
+	// decoded, so paths into any other field never match.
+	JSONPath string `json:"json_path,omitempty"`
+	// Regex is the pattern used by MatchTypeRegex, and optionally by
+	// MatchTypeJSONPath to match the path's result as a string instead of
+	// an exact comparison against Value.
+	Regex string `json:"regex,omitempty"`
+	// Value is the expected result compared against MatchTypeJSONPath's evaluation.
+	Value any `json:"value,omitempty"`
+	// ToolName is the function name matched by MatchTypeToolCall.
+	ToolName string `json:"tool_name,omitempty"`
+	// SystemContains, if set, additionally requires the request's
+	// systemInstruction to contain this substring.
+	SystemContains string `json:"system_contains,omitempty"`
+	// Matchers holds the sub-matchers for MatchTypeAll/MatchTypeAny.
+	Matchers []GoogleRequestMatch `json:"matchers,omitempty"`
 }
 
 // GoogleMock maps a Google request to a response using official SDK types
@@ -58,4 +183,112 @@ type GoogleMock struct {
 	Name     string                        `json:"name"`     // identifier for this mock
 	Match    GoogleRequestMatch            `json:"match"`    // Match type and value
 	Response genai.GenerateContentResponse `json:"response"` // Google response to return
+	// StreamChunks, if set, are emitted as "data: "-prefixed SSE frames, one
+	// GenerateContentResponse per frame, when the request is made against the
+	// :streamGenerateContent endpoint.
+	StreamChunks []genai.GenerateContentResponse `json:"stream_chunks,omitempty"`
+	// ChunkDelay is an optional delay applied between streamed chunks.
+	ChunkDelay time.Duration `json:"chunk_delay,omitempty"`
+	// Behavior's nonzero fields override Config.DefaultBehavior for this mock.
+	Behavior Behavior `json:"behavior,omitempty"`
+}
+
+// EmbeddingsRequestMatch matches an embeddings request by its input.
+type EmbeddingsRequestMatch struct {
+	MatchType MatchType `json:"match_type"`
+	Input     any       `json:"input"`
+}
+
+// EmbeddingsMock maps an OpenAI embeddings request to a response.
+type EmbeddingsMock struct {
+	Name     string                         `json:"name"`     // identifier for this mock
+	Match    EmbeddingsRequestMatch         `json:"match"`    // Match type and value
+	Response openai.CreateEmbeddingResponse `json:"response"` // OpenAI response to return
+}
+
+// ModerationRequestMatch matches a moderation request by its input.
+type ModerationRequestMatch struct {
+	MatchType MatchType `json:"match_type"`
+	Input     any       `json:"input"`
+}
+
+// ModerationMock maps an OpenAI moderation request to a response.
+type ModerationMock struct {
+	Name     string                       `json:"name"`     // identifier for this mock
+	Match    ModerationRequestMatch       `json:"match"`    // Match type and value
+	Response openai.ModerationNewResponse `json:"response"` // OpenAI response to return
+}
+
+// ImageRequestMatch matches an image generation request by its prompt.
+type ImageRequestMatch struct {
+	MatchType MatchType `json:"match_type"`
+	Prompt    string    `json:"prompt"`
+}
+
+// ImageMock maps an OpenAI image generation request to a response.
+type ImageMock struct {
+	Name     string                `json:"name"`     // identifier for this mock
+	Match    ImageRequestMatch     `json:"match"`    // Match type and value
+	Response openai.ImagesResponse `json:"response"` // OpenAI response to return
+}
+
+// TranscriptionRequestMatch matches an audio transcription request by the
+// uploaded file's name, since the request body carries audio, not text.
+type TranscriptionRequestMatch struct {
+	MatchType MatchType `json:"match_type"`
+	Filename  string    `json:"filename"`
+}
+
+// TranscriptionMock maps an OpenAI audio transcription request to a response.
+type TranscriptionMock struct {
+	Name     string                    `json:"name"`     // identifier for this mock
+	Match    TranscriptionRequestMatch `json:"match"`    // Match type and value
+	Response openai.Transcription      `json:"response"` // OpenAI response to return
+}
+
+// SpeechRequestMatch matches a text-to-speech request by its input text.
+type SpeechRequestMatch struct {
+	MatchType MatchType `json:"match_type"`
+	Input     string    `json:"input"`
+}
+
+// SpeechMock maps an OpenAI text-to-speech request to a raw audio response,
+// since the real endpoint returns binary audio rather than JSON.
+type SpeechMock struct {
+	Name  string             `json:"name"`  // identifier for this mock
+	Match SpeechRequestMatch `json:"match"` // Match type and value
+	// Audio is the raw audio bytes returned as the response body.
+	Audio []byte `json:"audio"`
+	// MimeType is the Content-Type of Audio. Defaults to "audio/mpeg".
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+// AnthropicCountTokensRequestMatch matches a count_tokens request's last
+// message against Message with MatchTypeExact or MatchTypeContains; the
+// other match types and SystemContains are not supported here.
+type AnthropicCountTokensRequestMatch struct {
+	MatchType MatchType              `json:"match_type"`
+	Message   anthropic.MessageParam `json:"message"`
+}
+
+// AnthropicCountTokensMock maps an Anthropic /v1/messages/count_tokens
+// request to a response.
+type AnthropicCountTokensMock struct {
+	Name     string                           `json:"name"`     // identifier for this mock
+	Match    AnthropicCountTokensRequestMatch `json:"match"`    // Match type and value
+	Response anthropic.MessageTokensCount     `json:"response"` // Anthropic response to return
+}
+
+// GoogleEmbedContentMock maps a Google :embedContent request to a response.
+type GoogleEmbedContentMock struct {
+	Name     string                     `json:"name"`     // identifier for this mock
+	Match    GoogleRequestMatch         `json:"match"`    // Match type and value
+	Response genai.EmbedContentResponse `json:"response"` // Google response to return
+}
+
+// GoogleCountTokensMock maps a Google :countTokens request to a response.
+type GoogleCountTokensMock struct {
+	Name     string                    `json:"name"`     // identifier for this mock
+	Match    GoogleRequestMatch        `json:"match"`    // Match type and value
+	Response genai.CountTokensResponse `json:"response"` // Google response to return
 }